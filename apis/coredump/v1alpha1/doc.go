@@ -15,4 +15,5 @@ limitations under the License.
 */
 
 // +k8s:deepcopy-gen=package
+// +groupName=coredump.k8s.io
 package v1alpha1
@@ -45,6 +45,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&CoredumpList{},
 		&CoredumpQuota{},
 		&CoredumpQuotaList{},
+		&CoredumpPolicy{},
+		&CoredumpPolicyList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -24,7 +25,43 @@ import (
 
 const CoredumpResourcePlural = "coredumps"
 const CoredumpQuotaResourcePlural = "coredumpquotas"
+const CoredumpPolicyResourcePlural = "coredumppolicies"
 
+// CoredumpNodeLabel is the label set on a Coredump object, its value is the
+// name of the node where the coredump file is cached.
+const CoredumpNodeLabel = GroupName + "/node"
+
+// CollectAnnotation is the annotation or label of Pod and Namespace objects,
+// coredumps are not collected if its value is "false". The value of pod
+// takes precedence over the value of namespace.
+const CollectAnnotation = GroupName + "/collect"
+
+// ExpireTimeAnnotation is the annotation of Coredump objects set by the
+// controller, its value is the time in RFC3339 format after which the
+// coredump is deleted.
+const ExpireTimeAnnotation = GroupName + "/expire-time"
+
+// RetentionAnnotation is the annotation of Namespace objects, its value is
+// how long coredumps in the namespace are kept after they happened, e.g.
+// "72h". The retention of coredump quotas takes precedence over it.
+const RetentionAnnotation = GroupName + "/retention"
+
+// SuppressedAnnotation is the annotation of Coredump objects set by
+// coredump-detector, its value is the number of coredumps of the container
+// dropped by the rate limit before this one.
+const SuppressedAnnotation = GroupName + "/suppressed"
+
+// RegistrationDelayAnnotation is the annotation of Coredump objects created
+// by coredump-agent after the apiserver was unreachable when the coredump
+// happened, its value is how late the object is created after the coredump,
+// e.g. "12m30s".
+const RegistrationDelayAnnotation = GroupName + "/registration-delay"
+
+// FileCleanupFinalizer is the finalizer of Coredump objects, it's removed
+// after the coredump file is removed.
+const FileCleanupFinalizer = GroupName + "/file-cleanup"
+
+// +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type Coredump struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -43,13 +80,91 @@ type CoredumpSpec struct {
 	Time metav1.Time `json:"dumptime"`
 	// Volume is the persistent volume, where coredump file is saved.
 	Volume string `json:"volume"`
-	// Size of coredump file
+	// Size of coredump file, it's the compressed size if the file is compressed.
 	Size *resource.Quantity `json:"size"`
+	// AllocatedSize is the disk space allocated for the coredump file, it's
+	// less than Size if zero blocks are stored as holes of a sparse file.
+	AllocatedSize *resource.Quantity `json:"allocatedSize,omitempty"`
+	// RawSize is the uncompressed size of coredump.
+	RawSize *resource.Quantity `json:"rawSize,omitempty"`
+	// Compression is the compression format of coredump file, empty means
+	// not compressed.
+	Compression string `json:"compression,omitempty"`
+	// Truncated is true if the coredump file is truncated at the size limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// PodLabels are the labels of the pod when coredump happens.
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+	// Crash is what caused the coredump, read from the ELF notes of the
+	// coredump. It's nil if the coredump has no file or can't be parsed.
+	Crash *CoredumpCrash `json:"crash,omitempty"`
+	// Modules are the executable and shared libraries mapped in the
+	// process, which are needed to symbolize the coredump.
+	Modules []CoredumpModule `json:"modules,omitempty"`
+}
+
+// CoredumpModule is a file mapped in the process.
+type CoredumpModule struct {
+	Path string `json:"path"`
+	// LoadAddress is the start of the mapping of the beginning of the file
+	// in hex.
+	LoadAddress string `json:"loadAddress"`
+	// BuildID is the GNU build-id of the file in hex, empty if the file has
+	// none or it can't be read.
+	BuildID string `json:"buildID,omitempty"`
+}
+
+// CoredumpCrash describes the crash of the process.
+type CoredumpCrash struct {
+	// Signal is the signal which caused the coredump, and SignalName is its
+	// name, e.g. SIGSEGV.
+	Signal     int    `json:"signal,omitempty"`
+	SignalName string `json:"signalName,omitempty"`
+	// SignalCode is si_code of the signal, e.g. 1 (SEGV_MAPERR) of SIGSEGV.
+	SignalCode int `json:"signalCode,omitempty"`
+	// FaultAddress is the address which caused the fault in hex, it's only
+	// set for signals like SIGSEGV and SIGBUS.
+	FaultAddress string `json:"faultAddress,omitempty"`
+	// TID is the thread which caused the coredump, as seen in the PID
+	// namespace of the process.
+	TID int `json:"tid,omitempty"`
+	// Cmdline is the command line of the process.
+	Cmdline string `json:"cmdline,omitempty"`
+	// Architecture is the machine of the process, e.g. x86_64.
+	Architecture string `json:"architecture,omitempty"`
+	// Registers are the general purpose registers of the thread in hex, e.g.
+	// "rip". They're only recorded for x86_64, aarch64 and i386.
+	Registers map[string]string `json:"registers,omitempty"`
 }
 
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
 type CoredumpStatus struct {
-	State   CoredumpState `json:"state,omitempty"`
-	Message string        `json:"message,omitempty"`
+	State CoredumpState `json:"state,omitempty"`
+	// Reason is a brief CamelCase reason of the state, e.g. NodeDiskPressure.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Conditions are the latest observations of the coredump.
+	Conditions []CoredumpCondition `json:"conditions,omitempty"`
+}
+
+type CoredumpConditionType string
+
+const (
+	// The coredump file failed to be removed when the object is deleted,
+	// the object is kept until the removal succeeds.
+	CoredumpFileCleanupFailed CoredumpConditionType = "FileCleanupFailed"
+)
+
+type CoredumpCondition struct {
+	Type               CoredumpConditionType  `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
 }
 
 type CoredumpState string
@@ -68,6 +183,12 @@ const (
 	CoredumpStateFailed CoredumpState = "FailedToSave"
 )
 
+const (
+	// The coredump didn't fit in the free space of the node, and it's
+	// discarded by coredump-detector. It's recorded as FailedToSave.
+	CoredumpReasonNodeDiskPressure = "NodeDiskPressure"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type CoredumpList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -75,6 +196,7 @@ type CoredumpList struct {
 	Items           []Coredump `json:"items"`
 }
 
+// +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type CoredumpQuota struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -90,11 +212,91 @@ type CoredumpQuotaList struct {
 	Items           []CoredumpQuota `json:"items"`
 }
 
+// QuotaSpec limits the coredumps consuming quota in the namespace, i.e.
+// coredumps which are allowed, saved or failed to save. All limits are
+// optional, and ExceedPolicy decides what happens to a coredump exceeding
+// any of them.
 type QuotaSpec struct {
-	Hard *resource.Quantity `json:"hard"`
+	// Hard is the max total size of coredumps.
+	Hard *resource.Quantity `json:"hard,omitempty"`
+	// MaxCount is the max number of coredumps.
+	MaxCount *int64 `json:"maxCount,omitempty"`
+	// PerPodHard is the max total size of coredumps of each pod.
+	PerPodHard *resource.Quantity `json:"perPodHard,omitempty"`
+	// PerContainerMaxCount is the max number of coredumps of each container
+	// of a pod.
+	PerContainerMaxCount *int64 `json:"perContainerMaxCount,omitempty"`
+	// Selector limits the quota to coredumps of pods selected by it. Nil
+	// means all pods.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// ExceedPolicy is the action taken when a coredump exceeds the quota,
+	// Deny by default.
+	ExceedPolicy QuotaExceedPolicy `json:"exceedPolicy,omitempty"`
+	// Retention is how long coredumps in the scope of the quota are kept
+	// after they happened. The retention of coredump policies takes
+	// precedence over it.
+	Retention *metav1.Duration `json:"retention,omitempty"`
 }
 
+type QuotaExceedPolicy string
+
+const (
+	// The coredump exceeding the quota is denied.
+	QuotaExceedDeny QuotaExceedPolicy = "Deny"
+	// The oldest saved coredumps are deleted until the coredump fits in
+	// the quota.
+	QuotaExceedEvictOldest QuotaExceedPolicy = "EvictOldest"
+)
+
 type QuotaStatus struct {
 	Used *resource.Quantity `json:"used"`
 	Hard *resource.Quantity `json:"hard"`
+	// Count is the number of coredumps consuming quota.
+	Count int64 `json:"count,omitempty"`
+	// Pods is the usage of each pod by pod name, it's only reported if
+	// perPodHard or perContainerMaxCount is set.
+	Pods map[string]PodQuotaUsage `json:"pods,omitempty"`
+}
+
+// PodQuotaUsage is the usage of quota by the coredumps of a pod.
+type PodQuotaUsage struct {
+	Used *resource.Quantity `json:"used"`
+	// Containers is the number of coredumps of each container.
+	Containers map[string]int64 `json:"containers,omitempty"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CoredumpPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              CoredumpPolicySpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CoredumpPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CoredumpPolicy `json:"items"`
+}
+
+// CoredumpPolicySpec defines which coredumps are collected in the namespace.
+// If there are policies in a namespace, a coredump is collected only when it
+// matches at least one of them, and the most restrictive limits of matched
+// policies apply.
+type CoredumpPolicySpec struct {
+	// Executables are executable filenames (without path prefix) whose
+	// coredumps are collected, shell file name patterns are supported.
+	// Empty means all executables.
+	Executables []string `json:"executables,omitempty"`
+	// Containers are names of containers whose coredumps are collected.
+	// Empty means all containers.
+	Containers []string `json:"containers,omitempty"`
+	// PodSelector selects pods whose coredumps are collected. Nil means all pods.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// MaxSize is the maximum size of a single coredump file.
+	MaxSize *resource.Quantity `json:"maxSize,omitempty"`
+	// Retention is how long a coredump is kept after it happened.
+	Retention *metav1.Duration `json:"retention,omitempty"`
 }
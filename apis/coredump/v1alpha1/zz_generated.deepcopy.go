@@ -16,64 +16,22 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// This file was autogenerated by deepcopy-gen. Do not edit it manually!
+// Code generated by deepcopy-gen. DO NOT EDIT.
 
 package v1alpha1
 
 import (
-	resource "k8s.io/apimachinery/pkg/api/resource"
-	conversion "k8s.io/apimachinery/pkg/conversion"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
-	reflect "reflect"
 )
 
-// GetGeneratedDeepCopyFuncs returns the generated funcs, since we aren't registering them.
-//
-// Deprecated: deepcopy registration will go away when static deepcopy is fully implemented.
-func GetGeneratedDeepCopyFuncs() []conversion.GeneratedDeepCopyFunc {
-	return []conversion.GeneratedDeepCopyFunc{
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*Coredump).DeepCopyInto(out.(*Coredump))
-			return nil
-		}, InType: reflect.TypeOf(&Coredump{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*CoredumpList).DeepCopyInto(out.(*CoredumpList))
-			return nil
-		}, InType: reflect.TypeOf(&CoredumpList{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*CoredumpQuota).DeepCopyInto(out.(*CoredumpQuota))
-			return nil
-		}, InType: reflect.TypeOf(&CoredumpQuota{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*CoredumpQuotaList).DeepCopyInto(out.(*CoredumpQuotaList))
-			return nil
-		}, InType: reflect.TypeOf(&CoredumpQuotaList{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*CoredumpSpec).DeepCopyInto(out.(*CoredumpSpec))
-			return nil
-		}, InType: reflect.TypeOf(&CoredumpSpec{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*CoredumpStatus).DeepCopyInto(out.(*CoredumpStatus))
-			return nil
-		}, InType: reflect.TypeOf(&CoredumpStatus{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*QuotaSpec).DeepCopyInto(out.(*QuotaSpec))
-			return nil
-		}, InType: reflect.TypeOf(&QuotaSpec{})},
-		{Fn: func(in interface{}, out interface{}, c *conversion.Cloner) error {
-			in.(*QuotaStatus).DeepCopyInto(out.(*QuotaStatus))
-			return nil
-		}, InType: reflect.TypeOf(&QuotaStatus{})},
-	}
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Coredump) DeepCopyInto(out *Coredump) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -91,9 +49,48 @@ func (in *Coredump) DeepCopy() *Coredump {
 func (in *Coredump) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
-	} else {
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpCondition) DeepCopyInto(out *CoredumpCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpCondition.
+func (in *CoredumpCondition) DeepCopy() *CoredumpCondition {
+	if in == nil {
 		return nil
 	}
+	out := new(CoredumpCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpCrash) DeepCopyInto(out *CoredumpCrash) {
+	*out = *in
+	if in.Registers != nil {
+		in, out := &in.Registers, &out.Registers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpCrash.
+func (in *CoredumpCrash) DeepCopy() *CoredumpCrash {
+	if in == nil {
+		return nil
+	}
+	out := new(CoredumpCrash)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -125,9 +122,137 @@ func (in *CoredumpList) DeepCopy() *CoredumpList {
 func (in *CoredumpList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
-	} else {
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpModule) DeepCopyInto(out *CoredumpModule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpModule.
+func (in *CoredumpModule) DeepCopy() *CoredumpModule {
+	if in == nil {
+		return nil
+	}
+	out := new(CoredumpModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpPolicy) DeepCopyInto(out *CoredumpPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpPolicy.
+func (in *CoredumpPolicy) DeepCopy() *CoredumpPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CoredumpPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CoredumpPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpPolicyList) DeepCopyInto(out *CoredumpPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CoredumpPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpPolicyList.
+func (in *CoredumpPolicyList) DeepCopy() *CoredumpPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(CoredumpPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CoredumpPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoredumpPolicySpec) DeepCopyInto(out *CoredumpPolicySpec) {
+	*out = *in
+	if in.Executables != nil {
+		in, out := &in.Executables, &out.Executables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.LabelSelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.MaxSize != nil {
+		in, out := &in.MaxSize, &out.MaxSize
+		if *in == nil {
+			*out = nil
+		} else {
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoredumpPolicySpec.
+func (in *CoredumpPolicySpec) DeepCopy() *CoredumpPolicySpec {
+	if in == nil {
 		return nil
 	}
+	out := new(CoredumpPolicySpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -154,9 +279,8 @@ func (in *CoredumpQuota) DeepCopy() *CoredumpQuota {
 func (in *CoredumpQuota) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
-	} else {
-		return nil
 	}
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -188,9 +312,8 @@ func (in *CoredumpQuotaList) DeepCopy() *CoredumpQuotaList {
 func (in *CoredumpQuotaList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
-	} else {
-		return nil
 	}
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -202,10 +325,49 @@ func (in *CoredumpSpec) DeepCopyInto(out *CoredumpSpec) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(resource.Quantity)
-			**out = (*in).DeepCopy()
+			x := (*in).DeepCopy()
+			*out = &x
 		}
 	}
+	if in.AllocatedSize != nil {
+		in, out := &in.AllocatedSize, &out.AllocatedSize
+		if *in == nil {
+			*out = nil
+		} else {
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.RawSize != nil {
+		in, out := &in.RawSize, &out.RawSize
+		if *in == nil {
+			*out = nil
+		} else {
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Crash != nil {
+		in, out := &in.Crash, &out.Crash
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(CoredumpCrash)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]CoredumpModule, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -222,6 +384,13 @@ func (in *CoredumpSpec) DeepCopy() *CoredumpSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CoredumpStatus) DeepCopyInto(out *CoredumpStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CoredumpCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -235,6 +404,38 @@ func (in *CoredumpStatus) DeepCopy() *CoredumpStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodQuotaUsage) DeepCopyInto(out *PodQuotaUsage) {
+	*out = *in
+	if in.Used != nil {
+		in, out := &in.Used, &out.Used
+		if *in == nil {
+			*out = nil
+		} else {
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodQuotaUsage.
+func (in *PodQuotaUsage) DeepCopy() *PodQuotaUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(PodQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *QuotaSpec) DeepCopyInto(out *QuotaSpec) {
 	*out = *in
@@ -243,8 +444,53 @@ func (in *QuotaSpec) DeepCopyInto(out *QuotaSpec) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(resource.Quantity)
-			**out = (*in).DeepCopy()
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.PerPodHard != nil {
+		in, out := &in.PerPodHard, &out.PerPodHard
+		if *in == nil {
+			*out = nil
+		} else {
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.PerContainerMaxCount != nil {
+		in, out := &in.PerContainerMaxCount, &out.PerContainerMaxCount
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(int64)
+			**out = **in
+		}
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.LabelSelector)
+			(*in).DeepCopyInto(*out)
+		}
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		if *in == nil {
+			*out = nil
+		} else {
+			*out = new(v1.Duration)
+			**out = **in
 		}
 	}
 	return
@@ -268,8 +514,8 @@ func (in *QuotaStatus) DeepCopyInto(out *QuotaStatus) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(resource.Quantity)
-			**out = (*in).DeepCopy()
+			x := (*in).DeepCopy()
+			*out = &x
 		}
 	}
 	if in.Hard != nil {
@@ -277,8 +523,17 @@ func (in *QuotaStatus) DeepCopyInto(out *QuotaStatus) {
 		if *in == nil {
 			*out = nil
 		} else {
-			*out = new(resource.Quantity)
-			**out = (*in).DeepCopy()
+			x := (*in).DeepCopy()
+			*out = &x
+		}
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make(map[string]PodQuotaUsage, len(*in))
+		for key, val := range *in {
+			newVal := new(PodQuotaUsage)
+			val.DeepCopyInto(newVal)
+			(*out)[key] = *newVal
 		}
 	}
 	return
@@ -19,23 +19,52 @@ package main
 
 import (
 	"context"
-	"flag"
+	"os"
 
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/apiextensions"
 	examplecontroller "k8s.io/coredump-detector/pkg/controller"
+	"k8s.io/coredump-detector/pkg/kube"
+	"k8s.io/coredump-detector/pkg/version"
 )
 
 func main() {
-	kubeconfig := flag.String("kubeconfig", "", "Path to a kube config. Only required if out-of-cluster.")
-	flag.Parse()
+	cco := options.NewCoredumpControllerOptions()
+	cco.AddFlags(pflag.CommandLine)
+
+	pflag.Parse()
+
+	if cco.PrintVersion {
+		version.PrintVersion()
+		os.Exit(0)
+	}
 
-	ctx, cancelFunc := context.WithCancel(context.Background())
-	defer cancelFunc()
+	if err := examplecontroller.CreateCustomResourceDefinition(cco.KubeConfig); err != nil {
+		glog.Fatalf("Failed to create custom resource definitions: %v", err)
+	}
 
-	examplecontroller.CreateCustomResourceDefinition(*kubeconfig)
+	kubeClient := kube.NewClientsetOrDie(cco.KubeConfig)
+	controller := examplecontroller.NewCoredumpController(apiextensions.NewCoredumpClientsetOrDie(cco.KubeConfig), kubeClient, cco)
+	run := func(stop <-chan struct{}) {
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		defer cancelFunc()
+		go func() {
+			<-stop
+			cancelFunc()
+		}()
+		if err := controller.Run(ctx); err != nil && err != context.Canceled {
+			glog.Error(err)
+		}
+	}
 
-	controller, err := examplecontroller.NewCoredumpController(*kubeconfig)
-	if err != nil {
-		panic(err)
+	if !cco.LeaderElect {
+		run(make(chan struct{}))
+		glog.Flush()
+		return
 	}
-	controller.Run(ctx)
+	examplecontroller.RunWithLeaderElection(kubeClient, cco, run)
+	glog.Flush()
 }
@@ -23,9 +23,11 @@ import (
 	"github.com/spf13/pflag"
 
 	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/agent"
+	"k8s.io/coredump-detector/pkg/apiextensions"
 	"k8s.io/coredump-detector/pkg/dump"
 	"k8s.io/coredump-detector/pkg/kube"
-	"k8s.io/coredump-detector/pkg/libdocker"
+	"k8s.io/coredump-detector/pkg/resolver"
 	"k8s.io/coredump-detector/pkg/version"
 )
 
@@ -35,17 +37,45 @@ func main() {
 	cdo.AddFlags(pflag.CommandLine)
 	po.AddFlags(pflag.CommandLine)
 
-	pflag.Parse()
+	// options of the flags file are overridden by the command line.
+	flags, flagsErr := options.ReadFlagsFile(options.DetectorFlagsFile)
+	pflag.CommandLine.Parse(append(flags, os.Args[1:]...))
+	if flagsErr != nil {
+		glog.Errorf("Failed to read options from %s: %v", options.DetectorFlagsFile, flagsErr)
+	}
+	if err := po.SetArgs(pflag.Args()); err != nil {
+		glog.Fatal(err)
+	}
 
 	if cdo.PrintVersion {
 		version.PrintVersion()
 		os.Exit(0)
 	}
+	if err := dump.CompleteOptions(cdo); err != nil {
+		glog.Fatal(err)
+	}
+
+	req := dump.NewRequest(po, cdo)
+	if req == nil {
+		// dropped by the rate limit, the core stream is discarded without
+		// reading.
+		glog.Flush()
+		return
+	}
+	if cdo.AgentSocket != "" {
+		if err := agent.Forward(req, os.Stdin, cdo); err != nil {
+			glog.Error(err)
+		}
+		glog.Flush()
+		return
+	}
+
 	kubeClient := kube.NewClientOrDie(cdo.KubeConfig)
-	dockerClient := libdocker.NewClientOrDie()
+	coredumpClient := apiextensions.NewCoredumpClientOrDie(cdo.KubeConfig)
+	containerResolver := resolver.NewResolverOrDie(cdo.ContainerRuntime, cdo.ContainerRuntimeEndpoint)
 
-	if err := dump.Dump(kubeClient, dockerClient, po, cdo); err != nil {
-		glog.Errorf(err.Error())
+	if err := dump.Dump(kubeClient, coredumpClient, containerResolver, req, os.Stdin, cdo); err != nil {
+		glog.Error(err)
 	}
 	glog.Flush()
 }
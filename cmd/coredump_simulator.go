@@ -0,0 +1,70 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// coredump-simulator sends a coredump to the core socket of coredump-agent
+// as the kernel does with core_pattern "@<socket>". The agent identifies the
+// simulator itself as the crashed process, so run it in a container of a pod
+// to test how coredumps of the pod are handled.
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/spf13/pflag"
+)
+
+func main() {
+	socket := pflag.String("socket", "/coredump/core.sock", "Core socket of coredump-agent")
+	core := pflag.String("core", "", "Coredump file to send, a coredump of zeros is sent if empty")
+	size := pflag.Int64("size", 1<<20, "Size of the coredump of zeros")
+	pflag.Parse()
+
+	var r io.Reader
+	if *core != "" {
+		f, err := os.Open(*core)
+		if err != nil {
+			glog.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	} else {
+		r = io.LimitReader(zeros{}, *size)
+	}
+
+	conn, err := net.Dial("unix", *socket)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	defer conn.Close()
+	n, err := io.Copy(conn, r)
+	if err != nil {
+		glog.Fatalf("Failed to send coredump after %d bytes: %v", n, err)
+	}
+	glog.Infof("Sent %d bytes of coredump of pid %d to %s", n, os.Getpid(), *socket)
+	glog.Flush()
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
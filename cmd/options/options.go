@@ -18,16 +18,128 @@ package options
 
 import (
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
+// DetectorFlagsFile is the file of command line options of coredump-detector.
+// The kernel keeps at most 127 characters of core_pattern, so core_pattern
+// only passes the process info, and the other options are read from this file.
+const DetectorFlagsFile = "/coredump/coredump-detector.flags"
+
 // CoredumpDetectorOptions contains node problem detector command line and application options.
 type CoredumpDetectorOptions struct {
 	// command line options
 	PrintVersion bool
 	KubeConfig   string
 	DumpDir      string
+	// ContainerRuntime is the runtime used to find the container of the dumped process.
+	ContainerRuntime string
+	// ContainerRuntimeEndpoint is the endpoint of the container runtime.
+	ContainerRuntimeEndpoint string
+	// Compression is the compression format of coredump files.
+	Compression string
+	// MaxDumpSize is the max stored size of a single coredump, empty means unlimited.
+	MaxDumpSize string
+	// OversizeAction is what to do with coredumps exceeding the quota or size limits.
+	OversizeAction string
+	// MinFreeBytes is the free space kept in the file system of DumpDir,
+	// empty means not set.
+	MinFreeBytes string
+	// MinFreePercent is the percentage of the file system of DumpDir kept
+	// free, 0 means not set.
+	MinFreePercent int
+	// RateLimitState is the file where token buckets of the rate limit are
+	// shared by coredump-detector processes in the node.
+	RateLimitState string
+	// ContainerRateLimit is the number of coredumps handled per minute for
+	// each container, 0 means unlimited.
+	ContainerRateLimit float64
+	ContainerBurst     int
+	// NodeRateLimit is the number of coredumps handled per minute in the
+	// node, 0 means unlimited.
+	NodeRateLimit float64
+	NodeBurst     int
+	// AgentSocket is the unix socket of coredump-agent. If it's set,
+	// coredump-detector only streams coredumps to the agent, which handles
+	// them.
+	AgentSocket string
+	// SpoolDir is where coredumps are spooled if the agent is down, they're
+	// handled by the agent later.
+	SpoolDir string
+}
+
+// CoredumpAgentOptions contains coredump agent command line and application
+// options. The agent handles coredumps as coredump-detector does, so it
+// shares the options of coredump-detector.
+type CoredumpAgentOptions struct {
+	CoredumpDetectorOptions
+	// NodeName is the name of node where the agent is running, pods of this
+	// node are cached.
+	NodeName     string
+	ResyncPeriod time.Duration
+	// SpoolPeriod is the period to handle coredumps spooled, to retry
+	// pending registrations of Coredump objects, and to report coredumps
+	// dropped by the rate limit.
+	SpoolPeriod time.Duration
+	// MaxConcurrentDumps is the max number of coredumps handled at the same
+	// time, coredump-detector waits for a free slot for a while, and then
+	// spools the coredump.
+	MaxConcurrentDumps int
+	// CoreSocket is the unix socket where the kernel sends coredumps, if
+	// core_pattern is "@<CoreSocket>". Empty means not listening.
+	CoreSocket string
+}
+
+// CoredumpSaverOptions contains coredump saver command line and application options.
+type CoredumpSaverOptions struct {
+	// command line options
+	PrintVersion bool
+	KubeConfig   string
+	DumpDir      string
+	// NodeName is the name of node where the saver is running, only coredumps
+	// cached in this node are saved by this saver.
+	NodeName string
+	// PVDir is the directory where the persistent volume is mounted.
+	PVDir string
+	// VolumeName is the name of persistent volume recorded in the coredump object.
+	VolumeName   string
+	ResyncPeriod time.Duration
+	// SweepPeriod is the period to remove orphaned files in host cache.
+	SweepPeriod time.Duration
+	// OrphanGracePeriod is how long a file is kept in host cache before it's
+	// removed as orphaned.
+	OrphanGracePeriod time.Duration
+	// MaxCacheSize is the max total size of host cache, like "10Gi". Empty
+	// means unlimited.
+	MaxCacheSize string
+}
+
+// CoredumpControllerOptions contains coredump controller command line and application options.
+type CoredumpControllerOptions struct {
+	// command line options
+	PrintVersion bool
+	KubeConfig   string
+	// Workers is the number of coredumps processed concurrently.
+	Workers      int
+	ResyncPeriod time.Duration
+	// QuotaReconcilePeriod is the period to check the usage of all quotas.
+	QuotaReconcilePeriod time.Duration
+	// DefaultRetention is how long coredumps are kept if no retention is
+	// set for them, 0 means forever.
+	DefaultRetention time.Duration
+	// LeaderElect enables leader election, so that multiple replicas can be
+	// run and only the leader processes coredumps.
+	LeaderElect              bool
+	LeaderElectNamespace     string
+	LeaderElectLeaseDuration time.Duration
+	LeaderElectRenewDeadline time.Duration
+	LeaderElectRetryPeriod   time.Duration
 }
 
 // ProgressInfo contains pid info passed by kernel
@@ -37,12 +149,27 @@ type ProgressInfo struct {
 	ContainerPid string // %p
 	Filename     string // %e
 	Time         string // %t
+	// Pidfd is the fd of the pidfd of dumped process, which is not reused
+	// like PID. Only the process which receives it can use it.
+	Pidfd string `json:"-"` // %F
 }
 
 func NewCoredumpDetectorOptions() *CoredumpDetectorOptions {
 	return &CoredumpDetectorOptions{}
 }
 
+func NewCoredumpSaverOptions() *CoredumpSaverOptions {
+	return &CoredumpSaverOptions{}
+}
+
+func NewCoredumpAgentOptions() *CoredumpAgentOptions {
+	return &CoredumpAgentOptions{}
+}
+
+func NewCoredumpControllerOptions() *CoredumpControllerOptions {
+	return &CoredumpControllerOptions{}
+}
+
 func NewProgressInfo() *ProgressInfo {
 	return &ProgressInfo{}
 }
@@ -52,6 +179,59 @@ func (cdo *CoredumpDetectorOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&cdo.PrintVersion, "version", false, "Print version information and quit")
 	fs.StringVarP(&cdo.KubeConfig, "kubeconfig", "c", "", "path to kubeconfig file")
 	fs.StringVarP(&cdo.DumpDir, "dump-dir", "d", "/var/coredump", "Directory where coredump files saved")
+	fs.StringVar(&cdo.ContainerRuntime, "container-runtime", "docker", "The container runtime to use. Possible values: 'docker', 'remote'")
+	fs.StringVar(&cdo.ContainerRuntimeEndpoint, "container-runtime-endpoint", "", "The endpoint of container runtime, e.g. unix:///run/containerd/containerd.sock for 'remote' runtime. Defaults to unix:///var/run/docker.sock for 'docker' runtime")
+	fs.StringVar(&cdo.Compression, "compression", "none", "Compress coredump files when saving them. Possible values: 'none', 'gzip', 'zstd'")
+	fs.StringVar(&cdo.MaxDumpSize, "max-dump-size", "", "The max stored size of a single coredump file, e.g. 1Gi. Empty means unlimited")
+	fs.StringVar(&cdo.OversizeAction, "oversize-action", "discard", "What to do with coredumps exceeding the namespace quota or size limits. Possible values: 'discard', 'truncate'")
+	fs.StringVar(&cdo.MinFreeBytes, "min-free-bytes", "", "The free space kept in the file system of dump dir, e.g. 1Gi. Coredumps exceeding the rest are discarded or truncated as 'oversize-action'")
+	fs.IntVar(&cdo.MinFreePercent, "min-free-percent", 5, "The percentage of the file system of dump dir kept free. The larger of it and 'min-free-bytes' is kept. 0 means not set")
+	fs.StringVar(&cdo.RateLimitState, "rate-limit-state", "/var/run/coredump-detector/ratelimit", "File where the rate limit state is shared by coredump-detector processes in the node")
+	fs.Float64Var(&cdo.ContainerRateLimit, "container-rate-limit", 2, "Number of coredumps handled per minute for each container, excess coredumps are dropped. 0 means unlimited")
+	fs.IntVar(&cdo.ContainerBurst, "container-burst", 5, "Number of coredumps of a container handled in a burst")
+	fs.Float64Var(&cdo.NodeRateLimit, "node-rate-limit", 20, "Number of coredumps handled per minute in the node, excess coredumps are dropped. 0 means unlimited")
+	fs.IntVar(&cdo.NodeBurst, "node-burst", 50, "Number of coredumps in the node handled in a burst")
+	fs.StringVar(&cdo.AgentSocket, "agent-socket", "", "Unix socket of coredump-agent. If set, coredumps are streamed to the agent, which handles them")
+	fs.StringVar(&cdo.SpoolDir, "spool-dir", "/coredump/spool", "Directory where coredumps are spooled if coredump-agent is down")
+}
+
+// AddFlags adds coredump agent command line options to pflag.
+func (cao *CoredumpAgentOptions) AddFlags(fs *pflag.FlagSet) {
+	cao.CoredumpDetectorOptions.AddFlags(fs)
+	fs.StringVar(&cao.NodeName, "node-name", "", "Name of the node where the agent runs")
+	fs.DurationVar(&cao.ResyncPeriod, "resync-period", 5*time.Minute, "Resync period of the caches of pods, namespaces, coredump policies and quotas")
+	fs.DurationVar(&cao.SpoolPeriod, "spool-period", time.Minute, "Period to handle coredumps spooled while the agent is down, to retry Coredump objects failed to be created, and to report coredumps dropped by the rate limit")
+	fs.IntVar(&cao.MaxConcurrentDumps, "max-concurrent-dumps", 4, "Max number of coredumps handled at the same time")
+	fs.StringVar(&cao.CoreSocket, "core-socket", "", "Unix socket where the kernel sends coredumps with core_pattern \"@<socket>\", requires Linux 6.16 or later. Not listening if empty")
+}
+
+// AddFlags adds coredump saver command line options to pflag.
+func (cso *CoredumpSaverOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&cso.PrintVersion, "version", false, "Print version information and quit")
+	fs.StringVarP(&cso.KubeConfig, "kubeconfig", "c", "", "path to kubeconfig file, in-cluster config is used if not set")
+	fs.StringVarP(&cso.DumpDir, "dump-dir", "d", "/var/coredump", "Directory where coredump files cached by coredump-detector")
+	fs.StringVar(&cso.NodeName, "node-name", "", "Name of the node where the saver runs")
+	fs.StringVar(&cso.PVDir, "pv-dir", "/pv", "Directory where the persistent volume is mounted")
+	fs.StringVar(&cso.VolumeName, "volume-name", "nfs", "Name of the persistent volume recorded in coredump objects")
+	fs.DurationVar(&cso.ResyncPeriod, "resync-period", 5*time.Minute, "Period to retry coredumps which are not saved yet")
+	fs.DurationVar(&cso.SweepPeriod, "sweep-period", 10*time.Minute, "Period to remove orphaned files in host cache")
+	fs.DurationVar(&cso.OrphanGracePeriod, "orphan-grace-period", time.Hour, "Files in host cache younger than it are never removed as orphaned")
+	fs.StringVar(&cso.MaxCacheSize, "max-cache-size", "", "Max total size of host cache, e.g. 10Gi. The oldest coredumps not saved yet are evicted if it's exceeded. Unlimited if empty")
+}
+
+// AddFlags adds coredump controller command line options to pflag.
+func (cco *CoredumpControllerOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&cco.PrintVersion, "version", false, "Print version information and quit")
+	fs.StringVarP(&cco.KubeConfig, "kubeconfig", "c", "", "Path to a kube config. Only required if out-of-cluster.")
+	fs.IntVar(&cco.Workers, "workers", 2, "Number of coredumps processed concurrently")
+	fs.DurationVar(&cco.ResyncPeriod, "resync-period", 5*time.Minute, "Period to resync all coredumps")
+	fs.DurationVar(&cco.QuotaReconcilePeriod, "quota-reconcile-period", 5*time.Minute, "Period to recompute the usage of all coredump quotas and correct drifted ones, 0 to disable")
+	fs.DurationVar(&cco.DefaultRetention, "default-retention", 0, "How long coredumps are kept after they happened, if no retention is set by coredump policies, quotas or the namespace. 0 means forever")
+	fs.BoolVar(&cco.LeaderElect, "leader-elect", true, "Elect a leader among replicas of the controller, only the leader processes coredumps")
+	fs.StringVar(&cco.LeaderElectNamespace, "leader-elect-namespace", "kube-system", "Namespace of the lock object used in leader election")
+	fs.DurationVar(&cco.LeaderElectLeaseDuration, "leader-elect-lease-duration", 15*time.Second, "Duration that non-leader candidates wait before trying to acquire leadership")
+	fs.DurationVar(&cco.LeaderElectRenewDeadline, "leader-elect-renew-deadline", 10*time.Second, "Duration that the leader retries refreshing leadership before giving up")
+	fs.DurationVar(&cco.LeaderElectRetryPeriod, "leader-elect-retry-period", 2*time.Second, "Duration that candidates wait between tries of actions")
 }
 
 // AddFlags add progress info command line options to pflag.
@@ -60,7 +240,39 @@ func (po *ProgressInfo) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVarP(&po.ContainerPid, "containerPid", "p", "", "PID of dumped process, as seen in the PID namespace in which the process resides")
 	fs.StringVarP(&po.Filename, "filename", "e", "", "executable filename (without path prefix)")
 	fs.StringVarP(&po.Time, "time", "t", "", "time of dump, expressed as seconds since the Epoch")
+	fs.StringVarP(&po.Pidfd, "pidfd", "F", "", "pidfd of dumped process, requires Linux 6.16 or later. If set, the PID of dumped process is read from it")
+
+}
+
+// SetArgs sets progress info from the positional arguments "%P %p %e %t [%F]"
+// of core_pattern, which follow "--" so that %e is never parsed as an option.
+// It does nothing if there is no positional argument.
+func (po *ProgressInfo) SetArgs(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if len(args) < 4 || len(args) > 5 {
+		return fmt.Errorf("expected arguments \"%%P %%p %%e %%t [%%F]\", got %q", args)
+	}
+	po.HostPid, po.ContainerPid, po.Filename, po.Time = args[0], args[1], args[2], args[3]
+	if len(args) == 5 {
+		// %F is empty on kernels which don't support it.
+		po.Pidfd = args[4]
+	}
+	return nil
+}
 
+// ReadFlagsFile reads command line options separated by white spaces from
+// file. It returns no option if the file doesn't exist.
+func ReadFlagsFile(name string) ([]string, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
 }
 
 func init() {
@@ -20,50 +20,14 @@
 # 1) cp coredump-detector binary to host
 # 2) set kubeconfig for coredump-detector
 # 3) set kernel.core_pattern
-# 4) mv core dump files to persistent volume
+# 4) start coredump-saver, which moves core dump files to persistent volume
+# If AGENT_SOCKET is set, coredump-detector only streams core dumps to
+# coredump-agent, which runs in another container and handles them. If
+# CORE_SOCKET is set, the kernel sends core dumps to coredump-agent directly,
+# which requires Linux 6.16 or later.
 
 set -x
 
-# save saves coredump file to persistent volume
-save() {
-	while read f; do
-		t=`file -b -i $f`
-		if [ "$t" = "application/x-coredump; charset=binary" ]; then
-			saveToPersistentVolume $f
-		fi
-	done
-}
-
-# /pv is a persistent volume in kubernetes cluster
-saveToPersistentVolume() {
-	d=`dirname $1`
-	if [ "$d" = "/var/coredump/others" ]; then
-		# coredump files out of k8s cluster
-		return
-	fi
-	dest=/pv/${d:14}
-	coredump=`basename $1`
-	namespace=`echo $1 | tr "/" "\n" | head -n 4 | tail -n 1`
-	state=`kubectl get coredump $coredump  -o go-template={{.status.state}} -n=$namespace`
-	if [ $? -eq 0 ]
-	then
-		if [ "$state" = "Allowed" ]; then
-			mkdir -p $dest
-			# we need to do tenant isolation for dump files, like using nfs access
-			# permissions, or publish core files in web application. 
-			mv $1 $dest
-			# set status
-			kubectl patch coredump $coredump -p  '{"status":{"message":"Saved to persistent volume","state":"Saved"}}' --type='merge' -n $namespace
-			# set persistent volume: pv-name:path
-			kubectl patch coredump $coredump -p  '{"spec":{"volume":"nfs:'${d:13}'"}}' --type='merge' -n $namespace
-		fi
-	else
-		# this should never happen
-		echo "Not found in apiserver, removed $1"
-		rm $1
-	fi
-}
-
 # start container with -v /coredump/:/coredump
 cp /coredump-detector /coredump/
 
@@ -74,11 +38,64 @@ sed -i "s/@TOKEN@/${token}/g" /config
 
 cp /config /coredump
 cp /run/secrets/kubernetes.io/serviceaccount/ca.crt /coredump/
-echo "|/coredump/coredump-detector -P=%P -p=%p -e=%e -t=%t -c=/coredump/config --log_dir=/coredump/ --v=10" > /proc/sys/kernel/core_pattern
+# CONTAINER_RUNTIME is "docker" or "remote", CONTAINER_RUNTIME_ENDPOINT is required by "remote" runtime.
+runtime="--container-runtime=${CONTAINER_RUNTIME:-docker}"
+if [ -n "${CONTAINER_RUNTIME_ENDPOINT}" ]; then
+	runtime="${runtime} --container-runtime-endpoint=${CONTAINER_RUNTIME_ENDPOINT}"
+fi
+# COMPRESSION is "none", "gzip" or "zstd"
+compression="--compression=${COMPRESSION:-none}"
+# MAX_DUMP_SIZE is a quantity like "1Gi", OVERSIZE_ACTION is "discard" or "truncate".
+limits=""
+if [ -n "${MAX_DUMP_SIZE}" ]; then
+	limits="--max-dump-size=${MAX_DUMP_SIZE}"
+fi
+if [ -n "${OVERSIZE_ACTION}" ]; then
+	limits="${limits} --oversize-action=${OVERSIZE_ACTION}"
+fi
+# MIN_FREE_BYTES is a quantity like "1Gi", MIN_FREE_PERCENT is 5 by default.
+if [ -n "${MIN_FREE_BYTES}" ]; then
+	limits="${limits} --min-free-bytes=${MIN_FREE_BYTES}"
+fi
+if [ -n "${MIN_FREE_PERCENT}" ]; then
+	limits="${limits} --min-free-percent=${MIN_FREE_PERCENT}"
+fi
+# CONTAINER_RATE_LIMIT and NODE_RATE_LIMIT are coredumps per minute, 0 means unlimited.
+ratelimits=""
+if [ -n "${CONTAINER_RATE_LIMIT}" ]; then
+	ratelimits="--container-rate-limit=${CONTAINER_RATE_LIMIT}"
+fi
+if [ -n "${NODE_RATE_LIMIT}" ]; then
+	ratelimits="${ratelimits} --node-rate-limit=${NODE_RATE_LIMIT}"
+fi
+# the kernel keeps at most 127 characters of core_pattern, so core_pattern only
+# passes the process info, and coredump-detector reads the other options from
+# /coredump/coredump-detector.flags. %F is dropped by kernels which don't
+# support it.
+if [ -n "${AGENT_SOCKET}" ]; then
+	# the other options are set on coredump-agent.
+	flags="--agent-socket=${AGENT_SOCKET} ${ratelimits}"
+else
+	flags="-c=/coredump/config ${runtime} ${compression} ${limits} ${ratelimits} --log_dir=/coredump/ --v=10"
+fi
+echo "${flags}" > /coredump/coredump-detector.flags
+if [ -n "${CORE_SOCKET}" ]; then
+	pattern="@${CORE_SOCKET}"
+else
+	pattern="|/coredump/coredump-detector -- %P %p %e %t %F"
+fi
+# the kernel silently truncates core_pattern longer than 127 characters, which
+# loses every coredump, e.g. with a long CORE_SOCKET.
+if [ ${#pattern} -gt 127 ]; then
+	echo "ERROR: core_pattern is longer than 127 characters: ${pattern}"
+	exit 1
+fi
+echo "${pattern}" > /proc/sys/kernel/core_pattern
 
-# start container with -v /var/coredump/:/var/coredump
-while true
-do
-	find /var/coredump/ -type f -mmin +4 | save
-	sleep 60
-done
+# MAX_CACHE_SIZE is a quantity like "10Gi", the host cache is unlimited if it's empty.
+cache=""
+if [ -n "${MAX_CACHE_SIZE}" ]; then
+	cache="--max-cache-size=${MAX_CACHE_SIZE}"
+fi
+# start container with -v /var/coredump/:/var/coredump and -v <pvc>:/pv
+exec /coredump-saver --node-name=${NODE_NAME} --dump-dir=/var/coredump --pv-dir=/pv ${cache} --v=5
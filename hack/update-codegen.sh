@@ -0,0 +1,43 @@
+#!/bin/bash
+#
+#Copyright 2017 The Kubernetes Authors All rights reserved.
+#
+#Licensed under the Apache License, Version 2.0 (the "License");
+#you may not use this file except in compliance with the License.
+#You may obtain a copy of the License at
+#
+#    http://www.apache.org/licenses/LICENSE-2.0
+#
+#Unless required by applicable law or agreed to in writing, software
+#distributed under the License is distributed on an "AS IS" BASIS,
+#WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+#See the License for the specific language governing permissions and
+#limitations under the License.
+
+# This script generates deepcopy functions, the typed clientset, listers and
+# informers of coredump.k8s.io. It requires the generators of
+# k8s.io/code-generator kubernetes-1.11.0 in ${GOPATH}/bin, which are installed by
+#   go get -d k8s.io/code-generator/cmd/...
+#   cd ${GOPATH}/src/k8s.io/code-generator && git checkout kubernetes-1.11.0 && go install ./cmd/...
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+PKG=k8s.io/coredump-detector
+APIS=${PKG}/apis/coredump/v1alpha1
+OUTPUT=${PKG}/pkg/client
+ROOT=$(dirname "${BASH_SOURCE}")/..
+BOILERPLATE=${ROOT}/hack/boilerplate.go.txt
+BIN=${GOPATH}/bin
+
+${BIN}/deepcopy-gen --input-dirs ${APIS} -O zz_generated.deepcopy \
+	--bounding-dirs ${PKG}/apis --go-header-file ${BOILERPLATE}
+${BIN}/client-gen --clientset-name versioned --input-base "" --input ${APIS} \
+	--output-package ${OUTPUT}/clientset --go-header-file ${BOILERPLATE}
+${BIN}/lister-gen --input-dirs ${APIS} \
+	--output-package ${OUTPUT}/listers --go-header-file ${BOILERPLATE}
+${BIN}/informer-gen --input-dirs ${APIS} \
+	--versioned-clientset-package ${OUTPUT}/clientset/versioned \
+	--listers-package ${OUTPUT}/listers \
+	--output-package ${OUTPUT}/informers --go-header-file ${BOILERPLATE}
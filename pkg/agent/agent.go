@@ -0,0 +1,235 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package agent implements coredump-agent, a long-running process in each
+// node which handles coredumps streamed by coredump-detector over a unix
+// socket. It keeps the clients and caches of objects across coredumps, so
+// the kernel-invoked coredump-detector is only a thin shim.
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/apiextensions"
+	informers "k8s.io/coredump-detector/pkg/client/informers/externalversions"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/kube"
+	"k8s.io/coredump-detector/pkg/resolver"
+)
+
+// Agent handles coredumps streamed by coredump-detector in the node.
+type Agent struct {
+	options        *options.CoredumpAgentOptions
+	kubeClient     kube.Client
+	coredumpClient apiextensions.CoredumpClient
+	runtime        resolver.RuntimeResolver
+	resolver       resolver.Resolver
+
+	informers       []cache.Controller
+	informerFactory informers.SharedInformerFactory
+
+	// slots limits the number of coredumps handled at the same time.
+	slots chan struct{}
+}
+
+// NewAgentOrDie returns an agent with clients of the apiserver and the
+// container runtime, and informers of pods in the node, namespaces, coredump
+// policies and quotas.
+func NewAgentOrDie(options *options.CoredumpAgentOptions) *Agent {
+	clientset := kube.NewClientsetOrDie(options.KubeConfig)
+	coredumpClientset := apiextensions.NewCoredumpClientsetOrDie(options.KubeConfig)
+	runtime := resolver.NewRuntimeResolverOrDie(options.ContainerRuntime, options.ContainerRuntimeEndpoint)
+
+	podSelector := fields.OneTermEqualSelector("spec.nodeName", options.NodeName)
+	pods, podInformer := cache.NewIndexerInformer(
+		cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "pods", metav1.NamespaceAll, podSelector),
+		&v1.Pod{}, options.ResyncPeriod, cache.ResourceEventHandlerFuncs{}, cache.Indexers{})
+	namespaces, namespaceInformer := cache.NewIndexerInformer(
+		cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "namespaces", metav1.NamespaceAll, fields.Everything()),
+		&v1.Namespace{}, options.ResyncPeriod, cache.ResourceEventHandlerFuncs{}, cache.Indexers{})
+	factory := informers.NewSharedInformerFactory(coredumpClientset, options.ResyncPeriod)
+	policyInformer := factory.Coredump().V1alpha1().CoredumpPolicies()
+	quotaInformer := factory.Coredump().V1alpha1().CoredumpQuotas()
+
+	return &Agent{
+		options: options,
+		kubeClient: &cachedKubeClient{
+			Client:     kube.NewClient(clientset),
+			pods:       pods,
+			namespaces: namespaces,
+		},
+		coredumpClient: &cachedCoredumpClient{
+			CoredumpClient: apiextensions.NewCoredumpClient(coredumpClientset),
+			policyLister:   policyInformer.Lister(),
+			quotaLister:    quotaInformer.Lister(),
+			policiesSynced: policyInformer.Informer().HasSynced,
+			quotasSynced:   quotaInformer.Informer().HasSynced,
+		},
+		runtime:         runtime,
+		resolver:        resolver.NewCgroupResolver(runtime),
+		informers:       []cache.Controller{podInformer, namespaceInformer},
+		informerFactory: factory,
+		slots:           make(chan struct{}, options.MaxConcurrentDumps),
+	}
+}
+
+// Run listens on the agent socket and handles coredumps until ctx is done.
+// Coredumps are accepted before the caches are synced, objects missing in
+// the caches are read from the apiserver.
+func (a *Agent) Run(ctx context.Context) error {
+	for _, informer := range a.informers {
+		go informer.Run(ctx.Done())
+	}
+	a.informerFactory.Start(ctx.Done())
+
+	listener, err := listen(ctx, a.options.AgentSocket)
+	if err != nil {
+		return err
+	}
+	if a.options.CoreSocket != "" {
+		coreListener, err := listen(ctx, a.options.CoreSocket)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		go func() {
+			if err := serve(ctx, coreListener, a.handleCore); err != nil && err != ctx.Err() {
+				glog.Errorf("Stopped listening on %s: %v", a.options.CoreSocket, err)
+			}
+		}()
+	}
+
+	go wait.Until(a.replaySpool, a.options.SpoolPeriod, ctx.Done())
+	go wait.Until(func() {
+		dump.ReplayPending(a.coredumpClient, &a.options.CoredumpDetectorOptions)
+	}, a.options.SpoolPeriod, ctx.Done())
+	go wait.Until(func() {
+		dump.ReportSuppressed(a.kubeClient, a.runtime, &a.options.CoredumpDetectorOptions, a.options.NodeName)
+	}, a.options.SpoolPeriod, ctx.Done())
+
+	return serve(ctx, listener, a.handle)
+}
+
+// listen listens on the unix socket until ctx is done. Only root can connect
+// to it.
+func listen(ctx context.Context, socket string) (net.Listener, error) {
+	// the socket is left by the previous agent.
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	glog.Infof("Listening on %s", socket)
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	return listener, nil
+}
+
+// serve handles connections of the listener until ctx is done.
+func serve(ctx context.Context, listener net.Listener, handle func(net.Conn)) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				glog.Errorf("Failed to accept connection: %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			return err
+		}
+		go handle(conn)
+	}
+}
+
+func (a *Agent) acquire() {
+	a.slots <- struct{}{}
+}
+
+// tryAcquire waits for a free slot for at most timeout.
+func (a *Agent) tryAcquire(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case a.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (a *Agent) release() {
+	<-a.slots
+}
+
+// handle reads the request from coredump-detector, and acks it once a slot
+// is free. Then the coredump streamed is handled as coredump-detector does.
+// If no slot is free in time, the request is rejected and the detector
+// spools the coredump, so connections don't pile up on a busy agent.
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		glog.Errorf("Failed to read request: %v", err)
+		return
+	}
+	req := &dump.Request{}
+	if err := json.Unmarshal(line, req); err != nil {
+		glog.Errorf("Invalid request %q: %v", string(line), err)
+		return
+	}
+	if !a.tryAcquire(acquireTimeout) {
+		glog.Warningf("No slot to handle coredump of %s (pid %s), it's spooled", req.Filename, req.HostPid)
+		conn.Write([]byte(busy + "\n"))
+		return
+	}
+	defer a.release()
+	if _, err := conn.Write([]byte(ack + "\n")); err != nil {
+		glog.Errorf("Failed to ack coredump of %s (pid %s): %v", req.Filename, req.HostPid, err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	glog.Infof("Handling coredump of %s (pid %s)", req.Filename, req.HostPid)
+	if err := dump.Dump(a.kubeClient, a.coredumpClient, a.resolver, req, reader, &a.options.CoredumpDetectorOptions); err != nil {
+		glog.Errorf("Failed to handle coredump of %s (pid %s): %v", req.Filename, req.HostPid, err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/apiextensions"
+	listers "k8s.io/coredump-detector/pkg/client/listers/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/kube"
+)
+
+// cachedKubeClient reads pods and namespaces from the informer caches. Objects
+// not in the caches yet, e.g. a pod just created, are read from the
+// apiserver. Objects returned must not be modified.
+type cachedKubeClient struct {
+	kube.Client
+	pods       cache.Indexer
+	namespaces cache.Indexer
+}
+
+func (c *cachedKubeClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	obj, exists, err := c.pods.GetByKey(namespace + "/" + name)
+	if err == nil && exists {
+		return obj.(*v1.Pod), nil
+	}
+	return c.Client.GetPod(namespace, name)
+}
+
+func (c *cachedKubeClient) GetNamespace(name string) (*v1.Namespace, error) {
+	obj, exists, err := c.namespaces.GetByKey(name)
+	if err == nil && exists {
+		return obj.(*v1.Namespace), nil
+	}
+	return c.Client.GetNamespace(name)
+}
+
+// cachedCoredumpClient lists coredump policies and quotas from the informer
+// caches once they're synced, until then they're listed from the apiserver.
+// Usage of quotas in the cache may be a little stale, the controller checks
+// the quotas again before coredumps are saved.
+type cachedCoredumpClient struct {
+	apiextensions.CoredumpClient
+	policyLister   listers.CoredumpPolicyLister
+	quotaLister    listers.CoredumpQuotaLister
+	policiesSynced cache.InformerSynced
+	quotasSynced   cache.InformerSynced
+}
+
+func (c *cachedCoredumpClient) ListCoredumpPolicies(namespace string) (*coredump.CoredumpPolicyList, error) {
+	if !c.policiesSynced() {
+		return c.CoredumpClient.ListCoredumpPolicies(namespace)
+	}
+	policies, err := c.policyLister.CoredumpPolicies(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	list := &coredump.CoredumpPolicyList{}
+	for _, p := range policies {
+		list.Items = append(list.Items, *p.DeepCopy())
+	}
+	return list, nil
+}
+
+func (c *cachedCoredumpClient) ListCoredumpQuotas(namespace string) (*coredump.CoredumpQuotaList, error) {
+	if !c.quotasSynced() {
+		return c.CoredumpClient.ListCoredumpQuotas(namespace)
+	}
+	quotas, err := c.quotaLister.CoredumpQuotas(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	list := &coredump.CoredumpQuotaList{}
+	for _, q := range quotas {
+		list.Items = append(list.Items, *q.DeepCopy())
+	}
+	return list, nil
+}
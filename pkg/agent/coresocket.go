@@ -0,0 +1,117 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/resolver"
+)
+
+// soPeerPidfd is SO_PEERPIDFD of Linux 6.5 or later, which returns a pidfd
+// of the peer process.
+const soPeerPidfd = 77
+
+// handleCore handles a coredump sent by the kernel with core_pattern
+// "@<socket>". The kernel connects to the socket on behalf of the crashed
+// process, so the process is identified by the peer credentials, and the
+// coredump is the whole stream.
+func (a *Agent) handleCore(conn net.Conn) {
+	defer conn.Close()
+	progressInfo, err := peerProcess(conn.(*net.UnixConn))
+	if err != nil {
+		glog.Errorf("Failed to identify the process of coredump: %v", err)
+		return
+	}
+	req := dump.NewRequest(progressInfo, &a.options.CoredumpDetectorOptions)
+	if req == nil {
+		// dropped by the rate limit.
+		return
+	}
+	if !a.tryAcquire(acquireTimeout) {
+		glog.Warningf("No slot to handle coredump of %s (pid %s), spool it", req.Filename, req.HostPid)
+		if err := Spool(req, conn, &a.options.CoredumpDetectorOptions); err != nil {
+			glog.Errorf("Failed to spool coredump of %s (pid %s): %v", req.Filename, req.HostPid, err)
+		}
+		return
+	}
+	defer a.release()
+
+	glog.Infof("Handling coredump of %s (pid %s) from kernel", req.Filename, req.HostPid)
+	if err := dump.Dump(a.kubeClient, a.coredumpClient, a.resolver, req, conn, &a.options.CoredumpDetectorOptions); err != nil {
+		glog.Errorf("Failed to handle coredump of %s (pid %s): %v", req.Filename, req.HostPid, err)
+	}
+}
+
+// peerProcess returns the process info of the peer of the connection, as
+// the kernel passes to core_pattern. The pidfd of the peer is preferred to
+// its PID if it's supported, so that a PID reused by another process isn't
+// mistaken for the crashed process.
+func peerProcess(conn *net.UnixConn) (*options.ProgressInfo, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	pidfd := -1
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+		if p, err := syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, soPeerPidfd); err == nil {
+			pidfd = p
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	pid := int(cred.Pid)
+	if pidfd >= 0 {
+		defer syscall.Close(pidfd)
+		if p, err := resolver.PidfdPid(pidfd); err == nil && p > 0 {
+			pid = p
+		}
+	}
+	process, err := resolver.ReadProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	if pidfd >= 0 {
+		// the information read is of the crashed process only if it's
+		// still alive.
+		if p, err := resolver.PidfdPid(pidfd); err != nil || p != pid {
+			return nil, fmt.Errorf("process %d exited", pid)
+		}
+	}
+	return &options.ProgressInfo{
+		HostPid:      strconv.Itoa(pid),
+		ContainerPid: strconv.Itoa(process.NSPid),
+		Filename:     process.Comm,
+		Time:         strconv.FormatInt(time.Now().Unix(), 10),
+	}, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+)
+
+// simulatorEnv makes the test binary act as coredump-simulator, which
+// streams a coredump to the core socket in the env, as the kernel does on
+// behalf of the crashed process.
+const simulatorEnv = "CORE_SOCKET_SIMULATOR"
+
+func TestMain(m *testing.M) {
+	if socket := os.Getenv(simulatorEnv); socket != "" {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			os.Exit(1)
+		}
+		conn.Write([]byte("\x7fELF"))
+		conn.(*net.UnixConn).CloseWrite()
+		// the process is alive until the agent closes the connection.
+		io.Copy(ioutil.Discard, conn)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCoreSocketPeer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coresocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "core.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	simulator := exec.Command(os.Args[0], "-test.run=^$")
+	simulator.Env = append(os.Environ(), simulatorEnv+"="+socket)
+	if err := simulator.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer simulator.Wait()
+	conn, err := listener.Accept()
+	if err != nil {
+		simulator.Process.Kill()
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	progressInfo, err := peerProcess(conn.(*net.UnixConn))
+	if err != nil {
+		t.Fatalf("peerProcess: %v", err)
+	}
+	pid := strconv.Itoa(simulator.Process.Pid)
+	if progressInfo.HostPid != pid {
+		t.Errorf("HostPid = %s, want %s of the simulator", progressInfo.HostPid, pid)
+	}
+	comm, err := ioutil.ReadFile("/proc/" + pid + "/comm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.TrimSpace(string(comm)); progressInfo.Filename != want {
+		t.Errorf("Filename = %q, want %q", progressInfo.Filename, want)
+	}
+	if progressInfo.ContainerPid == "" || progressInfo.Time == "" {
+		t.Errorf("ContainerPid and Time must be set: %+v", progressInfo)
+	}
+
+	cdo := &options.CoredumpDetectorOptions{
+		RateLimitState:     filepath.Join(dir, "ratelimit"),
+		ContainerRateLimit: 1,
+		ContainerBurst:     1,
+	}
+	req := dump.NewRequest(progressInfo, cdo)
+	if req == nil {
+		t.Fatal("the first coredump is dropped by the rate limit")
+	}
+	if req.HostPid != pid {
+		t.Errorf("HostPid of request = %s, want %s", req.HostPid, pid)
+	}
+	if !strings.Contains(req.Cmdline, "-test.run") {
+		t.Errorf("Cmdline = %q, want the command line of the simulator", req.Cmdline)
+	}
+	if dump.NewRequest(progressInfo, cdo) != nil {
+		t.Error("the second coredump exceeds the burst, but it's not dropped")
+	}
+	core, err := ioutil.ReadAll(conn)
+	if err != nil || string(core) != "\x7fELF" {
+		t.Errorf("coredump = %q, %v", core, err)
+	}
+}
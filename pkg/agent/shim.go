@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+)
+
+// The protocol between coredump-detector and coredump-agent. The detector
+// sends the request as a line of JSON, and the agent replies with a line of
+// ack once a slot is free to handle it, or busy if no slot is free in
+// acquireTimeout, then the detector spools the coredump. After the ack the
+// detector streams the coredump and closes the connection. The agent
+// handles the coredump without replying, so that the crashed process isn't
+// kept waiting for the apiserver.
+const (
+	ack  = "OK"
+	busy = "BUSY"
+	// handshakeTimeout is how long the detector waits for the agent to
+	// accept the coredump before it's spooled.
+	handshakeTimeout = 10 * time.Second
+	// acquireTimeout is how long the agent waits for a free slot, it's
+	// less than handshakeTimeout, so the detector gets the reply.
+	acquireTimeout = 5 * time.Second
+)
+
+// Forward streams the coredump read from core to coredump-agent. If the
+// agent doesn't accept it, the coredump is spooled, and the agent handles
+// it later.
+func Forward(req *dump.Request, core io.Reader, cdo *options.CoredumpDetectorOptions) error {
+	conn, err := handshake(cdo.AgentSocket, req)
+	if err != nil {
+		glog.Warningf("coredump-agent is not available, spool the coredump: %v", err)
+		return Spool(req, core, cdo)
+	}
+	defer conn.Close()
+	n, err := io.Copy(conn, core)
+	if err != nil {
+		// the agent may stop reading the coredump, e.g. it's denied.
+		glog.Infof("Streamed %d bytes of coredump to coredump-agent: %v", n, err)
+		return nil
+	}
+	glog.Infof("Streamed %d bytes of coredump to coredump-agent", n)
+	return nil
+}
+
+// handshake sends the request to the agent, and waits for the ack.
+func handshake(socket string, req *dump.Request) (*net.UnixConn, error) {
+	c, err := net.DialTimeout("unix", socket, handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := c.(*net.UnixConn)
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply = strings.TrimSpace(reply); reply != ack {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected reply %q", reply)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/resolver"
+	"k8s.io/coredump-detector/pkg/sparse"
+)
+
+// A spooled coredump is kept as two files in the spool dir, named after the
+// dump time and the pid: "<name>.core" is the coredump, and "<name>.json" is
+// the request. The request is written last, so a coredump is complete once
+// its request exists.
+const (
+	coreExt    = ".core"
+	requestExt = ".json"
+)
+
+// maxSpoolAttempts is the number of times a spooled coredump is handled
+// before it's dropped, about an hour with the default spool period.
+const maxSpoolAttempts = 60
+
+// permanentError is an error of a spooled coredump which can't be handled
+// by retrying it.
+type permanentError struct {
+	error
+}
+
+// isPermanent returns true if handling the spooled coredump failed for good,
+// e.g. its request is corrupted or its pod is deleted.
+func isPermanent(err error) bool {
+	if _, ok := err.(permanentError); ok {
+		return true
+	}
+	return err == dump.ErrPodNotFound || apierrors.IsNotFound(err)
+}
+
+// Spool writes the coredump read from core into the spool dir, it's limited
+// to the space available keeping the min free space.
+func Spool(req *dump.Request, core io.Reader, cdo *options.CoredumpDetectorOptions) error {
+	if err := os.MkdirAll(cdo.SpoolDir, 0700); err != nil {
+		return err
+	}
+	available, err := dump.AvailableBytes(cdo.SpoolDir, cdo)
+	if err != nil {
+		return err
+	}
+	if available == 0 {
+		glog.Warningf("Discarded coredump of %s (pid %s), no space left in %s", req.Filename, req.HostPid, cdo.SpoolDir)
+		return nil
+	}
+	if available > 0 {
+		// one more byte to find out whether the coredump exceeds it.
+		core = io.LimitReader(core, available+1)
+	}
+
+	name := filepath.Join(cdo.SpoolDir, req.Time+"-"+req.HostPid)
+	f, err := os.OpenFile(name+coreExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := sparse.Copy(f, core)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && available > 0 && n > available {
+		err = fmt.Errorf("no space left in %s", cdo.SpoolDir)
+	}
+	if err != nil {
+		os.Remove(name + coreExt)
+		return err
+	}
+
+	// the process exits once its coredump is read.
+	spooled := *req
+	spooled.Spooled = true
+	if err := writeRequest(name, &spooled); err != nil {
+		return err
+	}
+	glog.Infof("Spooled coredump of %s (pid %s) at %s", req.Filename, req.HostPid, name+coreExt)
+	return nil
+}
+
+// writeRequest writes the request of the spooled coredump name. It's
+// renamed into place, so it's never read partially written.
+func writeRequest(name string, req *dump.Request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name+requestExt+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(name+requestExt+".tmp", name+requestExt)
+}
+
+// readRequest reads the request of the spooled coredump name.
+func readRequest(name string) (*dump.Request, error) {
+	data, err := ioutil.ReadFile(name + requestExt)
+	if err != nil {
+		return nil, err
+	}
+	req := &dump.Request{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, permanentError{err}
+	}
+	return req, nil
+}
+
+// spooled returns the names of coredumps in the spool dir in the order of
+// dump time.
+func spooled(spoolDir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(spoolDir, "*"+requestExt))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(paths))
+	for _, p := range paths {
+		names = append(names, strings.TrimSuffix(p, requestExt))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// replaySpool handles the coredumps spooled while the agent was down. The
+// processes already exited, so their containers are looked up by the id
+// read when they crashed. Coredumps failed to handle are retried in the
+// next period up to maxSpoolAttempts times, and coredumps which can't be
+// handled, e.g. their pods are deleted, are dropped at once. A Warning
+// event of the node is recorded for coredumps dropped.
+func (a *Agent) replaySpool() {
+	names, err := spooled(a.options.SpoolDir)
+	if err != nil {
+		glog.Errorf("Failed to list spooled coredumps: %v", err)
+		return
+	}
+	for _, name := range names {
+		req, err := readRequest(name)
+		if err == nil {
+			err = a.replay(name, req)
+		}
+		switch {
+		case err == nil:
+		case isPermanent(err):
+			a.dropSpooled(name, req, err)
+		case req == nil:
+			glog.Errorf("Failed to read spooled coredump %s: %v", name, err)
+			continue
+		case req.Attempts+1 >= maxSpoolAttempts:
+			a.dropSpooled(name, req, fmt.Errorf("failed %d times, last error: %v", req.Attempts+1, err))
+		default:
+			glog.Errorf("Failed to handle spooled coredump %s, retry it: %v", name, err)
+			req.Attempts++
+			if err := writeRequest(name, req); err != nil {
+				glog.Errorf("Failed to count attempts of spooled coredump %s: %v", name, err)
+			}
+			continue
+		}
+		os.Remove(name + coreExt)
+		os.Remove(name + requestExt)
+	}
+}
+
+func (a *Agent) replay(name string, req *dump.Request) error {
+	f, err := os.Open(name + coreExt)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	a.acquire()
+	defer a.release()
+	r := resolver.NewIDResolver(a.runtime, req.ContainerID)
+	return dump.Dump(a.kubeClient, a.coredumpClient, r, req, f, &a.options.CoredumpDetectorOptions)
+}
+
+// dropSpooled records a Warning event of the node for the spooled coredump
+// name which is dropped. req is nil if the request can't be read.
+func (a *Agent) dropSpooled(name string, req *dump.Request, reason error) {
+	message := fmt.Sprintf("Dropped spooled coredump %s: %v", filepath.Base(name), reason)
+	if req != nil {
+		message = fmt.Sprintf("Dropped spooled coredump of %s (pid %s): %v", req.Filename, req.HostPid, reason)
+	}
+	glog.Warning(message)
+	if err := dump.RecordNodeEvent(a.kubeClient, a.options.NodeName, v1.EventTypeWarning, "CoredumpDropped", message); err != nil {
+		glog.Errorf("Failed to record event: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package agent
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/resolver"
+)
+
+// fakeKubeClient fails to get pods with podErr, and keeps events created.
+type fakeKubeClient struct {
+	podErr error
+	events map[string]*v1.Event
+}
+
+func (c *fakeKubeClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	return nil, c.podErr
+}
+
+func (c *fakeKubeClient) GetNamespace(name string) (*v1.Namespace, error) {
+	return nil, apierrors.NewNotFound(v1.Resource("namespaces"), name)
+}
+
+func (c *fakeKubeClient) GetEvent(namespace, name string) (*v1.Event, error) {
+	if event, ok := c.events[name]; ok {
+		return event, nil
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("events"), name)
+}
+
+func (c *fakeKubeClient) CreateEvent(event *v1.Event) (*v1.Event, error) {
+	c.events[event.ObjectMeta.Name] = event
+	return event, nil
+}
+
+func (c *fakeKubeClient) UpdateEvent(event *v1.Event) (*v1.Event, error) {
+	c.events[event.ObjectMeta.Name] = event
+	return event, nil
+}
+
+// fakeRuntime finds every container id in the pod web.
+type fakeRuntime struct{}
+
+func (fakeRuntime) Resolve(hostPid string) (*resolver.Container, error) {
+	return nil, nil
+}
+
+func (fakeRuntime) ResolveID(containerID string) (*resolver.Container, error) {
+	return &resolver.Container{ID: containerID, ContainerName: "app", Pod: "web", Namespace: "ns", Uid: "uid"}, nil
+}
+
+func TestReplaySpool(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		podErr   error
+		attempts int
+		// corrupted is true if the request isn't valid json.
+		corrupted bool
+		// wantKept is true if the coredump is kept to be retried.
+		wantKept bool
+	}{
+		{
+			name:      "corrupted request",
+			corrupted: true,
+		},
+		{
+			name:   "pod deleted",
+			podErr: apierrors.NewNotFound(v1.Resource("pods"), "web"),
+		},
+		{
+			name:     "apiserver unavailable",
+			podErr:   errors.New("connection refused"),
+			attempts: 3,
+			wantKept: true,
+		},
+		{
+			name:     "apiserver unavailable at the last attempt",
+			podErr:   errors.New("connection refused"),
+			attempts: maxSpoolAttempts - 1,
+		},
+	} {
+		dir, err := ioutil.TempDir("", "spool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		kc := &fakeKubeClient{podErr: tc.podErr, events: map[string]*v1.Event{}}
+		a := &Agent{
+			options: &options.CoredumpAgentOptions{
+				CoredumpDetectorOptions: options.CoredumpDetectorOptions{SpoolDir: dir},
+				NodeName:                "node1",
+			},
+			kubeClient: kc,
+			runtime:    fakeRuntime{},
+			slots:      make(chan struct{}, 1),
+		}
+
+		name := filepath.Join(dir, "1500000000-1234")
+		if err := ioutil.WriteFile(name+coreExt, []byte("\x7fELF"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		req := &dump.Request{
+			ProgressInfo: options.ProgressInfo{HostPid: "1234", ContainerPid: "1", Filename: "nginx", Time: "1500000000"},
+			ContainerID:  "container",
+			Spooled:      true,
+			Attempts:     tc.attempts,
+		}
+		if tc.corrupted {
+			err = ioutil.WriteFile(name+requestExt, []byte("{"), 0600)
+		} else {
+			err = writeRequest(name, req)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		a.replaySpool()
+
+		_, err = os.Stat(name + coreExt)
+		if kept := err == nil; kept != tc.wantKept {
+			t.Errorf("%s: coredump kept = %v, want %v", tc.name, kept, tc.wantKept)
+		}
+		event := kc.events["node1.coredumpdropped"]
+		if tc.wantKept {
+			if event != nil {
+				t.Errorf("%s: recorded event %q for a coredump kept", tc.name, event.Message)
+			}
+			if retried, err := readRequest(name); err != nil || retried.Attempts != tc.attempts+1 {
+				t.Errorf("%s: request = %+v, %v, want %d attempts", tc.name, retried, err, tc.attempts+1)
+			}
+			continue
+		}
+		if _, err := os.Stat(name + requestExt); !os.IsNotExist(err) {
+			t.Errorf("%s: request is kept for a coredump dropped: %v", tc.name, err)
+		}
+		if event == nil || event.Type != v1.EventTypeWarning || event.InvolvedObject.Kind != "Node" {
+			t.Errorf("%s: event = %+v, want a Warning event of the node", tc.name, event)
+		} else if !tc.corrupted && !strings.Contains(event.Message, "nginx (pid 1234)") {
+			t.Errorf("%s: event message = %q, want the process dropped", tc.name, event.Message)
+		}
+	}
+}
@@ -25,19 +25,25 @@ import (
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 
 	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/client/clientset/versioned"
 )
 
 type CrdClient interface {
 	CreateCoredumpDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error)
 	CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error)
+	CreateCoredumpPolicyDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error)
+	// UpdateDefinitions updates the validation schema, subresources and
+	// printer columns of existing CRDs to the ones of this version.
+	UpdateDefinitions() error
 }
 
 type crdClient struct {
@@ -54,85 +60,57 @@ func newClientsetOrDie(kubeConfig string) *apiextensionsclient.Clientset {
 	// use the current context in kubeconfig
 	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
 
 	// create the clientset
 	clientset, err := apiextensionsclient.NewForConfig(config)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
 	return clientset
 }
 
-const exampleCRDName = coredump.CoredumpResourcePlural + "." + coredump.GroupName
-const exampleCRDQuotaName = coredump.CoredumpQuotaResourcePlural + "." + coredump.GroupName
-
 func (c *crdClient) CreateCoredumpDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error) {
-	crd := &apiextensionsv1beta1.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: exampleCRDName,
-		},
-		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
-			Group:   coredump.GroupName,
-			Version: coredump.SchemeGroupVersion.Version,
-			Scope:   apiextensionsv1beta1.NamespaceScoped,
-			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
-				Plural: coredump.CoredumpResourcePlural,
-				Kind:   reflect.TypeOf(coredump.Coredump{}).Name(),
-			},
-		},
-	}
-	_, err := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
-	if err != nil {
-		return nil, err
-	}
+	return c.createDefinition(newCoredumpDefinition())
+}
 
-	// wait for CRD being established
-	err = wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
-		crd, err = c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(exampleCRDName, metav1.GetOptions{})
+func (c *crdClient) CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error) {
+	return c.createDefinition(newCoredumpQuotaDefinition())
+}
+
+func (c *crdClient) CreateCoredumpPolicyDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error) {
+	return c.createDefinition(newCoredumpPolicyDefinition())
+}
+
+func (c *crdClient) UpdateDefinitions() error {
+	for _, desired := range []*apiextensionsv1beta1.CustomResourceDefinition{
+		newCoredumpDefinition(),
+		newCoredumpQuotaDefinition(),
+		newCoredumpPolicyDefinition(),
+	} {
+		crd, err := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(desired.Name, metav1.GetOptions{})
 		if err != nil {
-			return false, err
+			return err
 		}
-		for _, cond := range crd.Status.Conditions {
-			switch cond.Type {
-			case apiextensionsv1beta1.Established:
-				if cond.Status == apiextensionsv1beta1.ConditionTrue {
-					return true, err
-				}
-			case apiextensionsv1beta1.NamesAccepted:
-				if cond.Status == apiextensionsv1beta1.ConditionFalse {
-					fmt.Printf("Name conflict: %v\n", cond.Reason)
-				}
-			}
+		if reflect.DeepEqual(crd.Spec.Validation, desired.Spec.Validation) &&
+			reflect.DeepEqual(crd.Spec.Subresources, desired.Spec.Subresources) &&
+			reflect.DeepEqual(crd.Spec.AdditionalPrinterColumns, desired.Spec.AdditionalPrinterColumns) {
+			continue
 		}
-		return false, err
-	})
-	if err != nil {
-		deleteErr := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(exampleCRDName, nil)
-		if deleteErr != nil {
-			return nil, errors.NewAggregate([]error{err, deleteErr})
+		crd.Spec.Validation = desired.Spec.Validation
+		crd.Spec.Subresources = desired.Spec.Subresources
+		crd.Spec.AdditionalPrinterColumns = desired.Spec.AdditionalPrinterColumns
+		if _, err := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Update(crd); err != nil {
+			return err
 		}
-		return nil, err
 	}
-	return crd, nil
+	return nil
 }
 
-func (c *crdClient) CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.CustomResourceDefinition, error) {
-	crd := &apiextensionsv1beta1.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: exampleCRDQuotaName,
-		},
-		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
-			Group:   coredump.GroupName,
-			Version: coredump.SchemeGroupVersion.Version,
-			Scope:   apiextensionsv1beta1.NamespaceScoped,
-			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
-				Plural: coredump.CoredumpQuotaResourcePlural,
-				Kind:   reflect.TypeOf(coredump.CoredumpQuota{}).Name(),
-			},
-		},
-	}
+// createDefinition creates the CRD, and waits for it being established.
+func (c *crdClient) createDefinition(crd *apiextensionsv1beta1.CustomResourceDefinition) (*apiextensionsv1beta1.CustomResourceDefinition, error) {
+	name := crd.ObjectMeta.Name
 	_, err := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Create(crd)
 	if err != nil {
 		return nil, err
@@ -140,7 +118,7 @@ func (c *crdClient) CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.Custo
 
 	// wait for CRD being established
 	err = wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
-		crd, err = c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(exampleCRDQuotaName, metav1.GetOptions{})
+		crd, err = c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
 		if err != nil {
 			return false, err
 		}
@@ -159,7 +137,7 @@ func (c *crdClient) CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.Custo
 		return false, err
 	})
 	if err != nil {
-		deleteErr := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(exampleCRDQuotaName, nil)
+		deleteErr := c.clientset.ApiextensionsV1beta1().CustomResourceDefinitions().Delete(name, nil)
 		if deleteErr != nil {
 			return nil, errors.NewAggregate([]error{err, deleteErr})
 		}
@@ -170,50 +148,79 @@ func (c *crdClient) CreateCoredumpQuotaDefinition() (*apiextensionsv1beta1.Custo
 
 type CoredumpClient interface {
 	CreateCoredump(*coredump.Coredump, string) (*coredump.Coredump, error)
+	GetCoredump(namespace, name string) (*coredump.Coredump, error)
+	UpdateCoredump(*coredump.Coredump) (*coredump.Coredump, error)
+	// UpdateCoredumpStatus updates the status subresource, changes to other
+	// fields are ignored.
+	UpdateCoredumpStatus(*coredump.Coredump) (*coredump.Coredump, error)
+	ListCoredumpPolicies(string) (*coredump.CoredumpPolicyList, error)
+	ListCoredumpQuotas(string) (*coredump.CoredumpQuotaList, error)
+	// ListWatchCoredumps returns a ListerWatcher of coredumps in all namespaces
+	// which match the label selector.
+	ListWatchCoredumps(selector labels.Selector) cache.ListerWatcher
 }
 
 type coredumpClient struct {
-	clientset *rest.RESTClient
+	clientset versioned.Interface
 }
 
 func NewCoredumpClientOrDie(kubeConfig string) CoredumpClient {
-	c := &coredumpClient{}
-	c.clientset = newCoredumpClientsetOrDie(kubeConfig)
-	return c
+	return NewCoredumpClient(NewCoredumpClientsetOrDie(kubeConfig))
 }
 
-func newCoredumpClientsetOrDie(kubeConfig string) *rest.RESTClient {
-	scheme := runtime.NewScheme()
-	if err := coredump.AddToScheme(scheme); err != nil {
-		glog.Errorf(err.Error())
-	}
+// NewCoredumpClient returns the client backed by the clientset.
+func NewCoredumpClient(clientset versioned.Interface) CoredumpClient {
+	return &coredumpClient{clientset: clientset}
+}
 
+// NewCoredumpClientsetOrDie returns the generated clientset of coredump.k8s.io.
+func NewCoredumpClientsetOrDie(kubeConfig string) versioned.Interface {
 	// use the current context in kubeconfig
 	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
 
 	// create the clientset
-	config.GroupVersion = &coredump.SchemeGroupVersion
-	config.APIPath = "/apis"
-	config.ContentType = runtime.ContentTypeJSON
-	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
-
-	client, err := rest.RESTClientFor(config)
+	clientset, err := versioned.NewForConfig(config)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
-
-	return client
+	return clientset
 }
 
 func (c *coredumpClient) CreateCoredump(cd *coredump.Coredump, namespace string) (*coredump.Coredump, error) {
-	var result coredump.Coredump
-	err := c.clientset.Post().
-		Resource(coredump.CoredumpResourcePlural).
-		Namespace(namespace).
-		Body(cd).
-		Do().Into(&result)
-	return &result, err
+	return c.clientset.CoredumpV1alpha1().Coredumps(namespace).Create(cd)
+}
+
+func (c *coredumpClient) GetCoredump(namespace, name string) (*coredump.Coredump, error) {
+	return c.clientset.CoredumpV1alpha1().Coredumps(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *coredumpClient) UpdateCoredump(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	return c.clientset.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).Update(cd)
+}
+
+func (c *coredumpClient) UpdateCoredumpStatus(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	return c.clientset.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).UpdateStatus(cd)
+}
+
+func (c *coredumpClient) ListCoredumpPolicies(namespace string) (*coredump.CoredumpPolicyList, error) {
+	return c.clientset.CoredumpV1alpha1().CoredumpPolicies(namespace).List(metav1.ListOptions{})
+}
+
+func (c *coredumpClient) ListCoredumpQuotas(namespace string) (*coredump.CoredumpQuotaList, error) {
+	return c.clientset.CoredumpV1alpha1().CoredumpQuotas(namespace).List(metav1.ListOptions{})
+}
+
+func (c *coredumpClient) ListWatchCoredumps(selector labels.Selector) cache.ListerWatcher {
+	listFunc := func(options metav1.ListOptions) (runtime.Object, error) {
+		options.LabelSelector = selector.String()
+		return c.clientset.CoredumpV1alpha1().Coredumps(metav1.NamespaceAll).List(options)
+	}
+	watchFunc := func(options metav1.ListOptions) (watch.Interface, error) {
+		options.LabelSelector = selector.String()
+		return c.clientset.CoredumpV1alpha1().Coredumps(metav1.NamespaceAll).Watch(options)
+	}
+	return &cache.ListWatch{ListFunc: listFunc, WatchFunc: watchFunc}
 }
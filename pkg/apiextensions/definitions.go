@@ -0,0 +1,246 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiextensions
+
+import (
+	"encoding/json"
+	"reflect"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+const exampleCRDName = coredump.CoredumpResourcePlural + "." + coredump.GroupName
+const exampleCRDQuotaName = coredump.CoredumpQuotaResourcePlural + "." + coredump.GroupName
+const exampleCRDPolicyName = coredump.CoredumpPolicyResourcePlural + "." + coredump.GroupName
+
+// newDefinition returns a namespaced CRD of coredump.k8s.io.
+func newDefinition(name, plural, singular string, obj interface{}) *apiextensionsv1beta1.CustomResourceDefinition {
+	kind := reflect.TypeOf(obj).Name()
+	return &apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   coredump.GroupName,
+			Version: coredump.SchemeGroupVersion.Version,
+			Scope:   apiextensionsv1beta1.NamespaceScoped,
+			Names: apiextensionsv1beta1.CustomResourceDefinitionNames{
+				Plural:   plural,
+				Singular: singular,
+				Kind:     kind,
+				ListKind: kind + "List",
+			},
+		},
+	}
+}
+
+func newCoredumpDefinition() *apiextensionsv1beta1.CustomResourceDefinition {
+	crd := newDefinition(exampleCRDName, coredump.CoredumpResourcePlural, "coredump", coredump.Coredump{})
+	crd.Spec.Validation = &apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
+			Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+				"spec": {
+					Type:     "object",
+					Required: []string{"containerName", "pod", "uid", "filename", "dumptime"},
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"containerName": {Type: "string"},
+						"pod":           {Type: "string"},
+						"uid":           {Type: "string"},
+						"pid":           {Type: "integer", Minimum: float64Ptr(0)},
+						"filename":      {Type: "string"},
+						"dumptime":      {Type: "string", Format: "date-time"},
+						"volume":        {Type: "string"},
+						"size":          quantitySchema,
+						"allocatedSize": quantitySchema,
+						"rawSize":       quantitySchema,
+						"compression": {
+							Type: "string",
+							Enum: enum(coredump.CompressionNone, coredump.CompressionGzip, coredump.CompressionZstd),
+						},
+						"truncated": {Type: "boolean"},
+						"podLabels": {
+							Type:                 "object",
+							AdditionalProperties: &apiextensionsv1beta1.JSONSchemaPropsOrBool{Schema: &apiextensionsv1beta1.JSONSchemaProps{Type: "string"}},
+						},
+						"crash": {
+							Type: "object",
+							Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+								"signal":       {Type: "integer"},
+								"signalName":   {Type: "string"},
+								"signalCode":   {Type: "integer"},
+								"faultAddress": {Type: "string"},
+								"tid":          {Type: "integer"},
+								"cmdline":      {Type: "string"},
+								"architecture": {Type: "string"},
+								"registers": {
+									Type:                 "object",
+									AdditionalProperties: &apiextensionsv1beta1.JSONSchemaPropsOrBool{Schema: &apiextensionsv1beta1.JSONSchemaProps{Type: "string"}},
+								},
+							},
+						},
+						"modules": {
+							Type: "array",
+							Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1beta1.JSONSchemaProps{
+								Type:     "object",
+								Required: []string{"path", "loadAddress"},
+								Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+									"path":        {Type: "string"},
+									"loadAddress": {Type: "string"},
+									"buildID":     {Type: "string"},
+								},
+							}},
+						},
+					},
+				},
+				"status": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"state": {
+							Type: "string",
+							Enum: enum(
+								string(coredump.CoredumpStateCreated),
+								string(coredump.CoredumpStateDenied),
+								string(coredump.CoredumpStateStateAllowed),
+								string(coredump.CoredumpStateProcessed),
+								string(coredump.CoredumpStateFailed),
+							),
+						},
+						"reason":  {Type: "string"},
+						"message": {Type: "string"},
+						"conditions": {
+							Type: "array",
+							Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1beta1.JSONSchemaProps{
+								Type:     "object",
+								Required: []string{"type", "status"},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	crd.Spec.Subresources = &apiextensionsv1beta1.CustomResourceSubresources{
+		Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+	}
+	crd.Spec.AdditionalPrinterColumns = []apiextensionsv1beta1.CustomResourceColumnDefinition{
+		{Name: "Pod", Type: "string", JSONPath: ".spec.pod"},
+		{Name: "Container", Type: "string", JSONPath: ".spec.containerName"},
+		{Name: "Executable", Type: "string", JSONPath: ".spec.filename"},
+		{Name: "Signal", Type: "string", JSONPath: ".spec.crash.signalName"},
+		{Name: "Size", Type: "string", JSONPath: ".spec.size"},
+		{Name: "State", Type: "string", JSONPath: ".status.state"},
+		ageColumn,
+	}
+	return crd
+}
+
+func newCoredumpQuotaDefinition() *apiextensionsv1beta1.CustomResourceDefinition {
+	crd := newDefinition(exampleCRDQuotaName, coredump.CoredumpQuotaResourcePlural, "coredumpquota", coredump.CoredumpQuota{})
+	crd.Spec.Validation = &apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
+			Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"hard":                 quantitySchema,
+						"maxCount":             {Type: "integer", Minimum: float64Ptr(0)},
+						"perPodHard":           quantitySchema,
+						"perContainerMaxCount": {Type: "integer", Minimum: float64Ptr(0)},
+						"selector":             {Type: "object"},
+						"exceedPolicy": {
+							Type: "string",
+							Enum: enum(string(coredump.QuotaExceedDeny), string(coredump.QuotaExceedEvictOldest)),
+						},
+						"retention": {Type: "string"},
+					},
+				},
+				"status": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"hard":  quantitySchema,
+						"used":  quantitySchema,
+						"count": {Type: "integer"},
+						"pods":  {Type: "object"},
+					},
+				},
+			},
+		},
+	}
+	crd.Spec.Subresources = &apiextensionsv1beta1.CustomResourceSubresources{
+		Status: &apiextensionsv1beta1.CustomResourceSubresourceStatus{},
+	}
+	crd.Spec.AdditionalPrinterColumns = []apiextensionsv1beta1.CustomResourceColumnDefinition{
+		{Name: "Hard", Type: "string", JSONPath: ".spec.hard"},
+		{Name: "Used", Type: "string", JSONPath: ".status.used"},
+		{Name: "Max Count", Type: "integer", JSONPath: ".spec.maxCount"},
+		{Name: "Count", Type: "integer", JSONPath: ".status.count"},
+		ageColumn,
+	}
+	return crd
+}
+
+func newCoredumpPolicyDefinition() *apiextensionsv1beta1.CustomResourceDefinition {
+	crd := newDefinition(exampleCRDPolicyName, coredump.CoredumpPolicyResourcePlural, "coredumppolicy", coredump.CoredumpPolicy{})
+	stringArray := apiextensionsv1beta1.JSONSchemaProps{
+		Type:  "array",
+		Items: &apiextensionsv1beta1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1beta1.JSONSchemaProps{Type: "string"}},
+	}
+	crd.Spec.Validation = &apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{
+			Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+				"spec": {
+					Type: "object",
+					Properties: map[string]apiextensionsv1beta1.JSONSchemaProps{
+						"executables": stringArray,
+						"containers":  stringArray,
+						"podSelector": {Type: "object"},
+						"maxSize":     quantitySchema,
+						// metav1.Duration is a string like "72h".
+						"retention": {Type: "string"},
+					},
+				},
+			},
+		},
+	}
+	return crd
+}
+
+// quantitySchema is the schema of resource.Quantity, which is a string like
+// "1Gi" or a number, so the type isn't restricted.
+var quantitySchema = apiextensionsv1beta1.JSONSchemaProps{}
+
+var ageColumn = apiextensionsv1beta1.CustomResourceColumnDefinition{
+	Name:     "Age",
+	Type:     "date",
+	JSONPath: ".metadata.creationTimestamp",
+}
+
+func enum(values ...string) []apiextensionsv1beta1.JSON {
+	result := make([]apiextensionsv1beta1.JSON, 0, len(values))
+	for _, v := range values {
+		raw, _ := json.Marshal(v)
+		result = append(result, apiextensionsv1beta1.JSON{Raw: raw})
+	}
+	return result
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
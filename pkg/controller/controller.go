@@ -19,236 +19,336 @@ package controller
 import (
 	"context"
 	"fmt"
-
-	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer"
-	"k8s.io/client-go/rest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
-	//"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 
 	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/client/clientset/versioned"
+	informers "k8s.io/coredump-detector/pkg/client/informers/externalversions"
+	listers "k8s.io/coredump-detector/pkg/client/listers/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/policy"
 )
 
-// Watcher is an example of watching on resource create/update/delete events
+// maxRetries is the number of times a namespace is retried before it's
+// dropped out of the queue. With the default rate limiter, the last retry
+// is about 82 seconds after the first failure.
+const maxRetries = 15
+
+// CoredumpController admits coredumps registered by coredump-detector. It
+// checks the policies and quotas of the namespace, marks coredumps as
+// Allowed or Denied, and keeps the usage of quotas up to date.
 type CoredumpController struct {
-	CoredumpClient *rest.RESTClient
-	CoredumpScheme *runtime.Scheme
+	CoredumpClient versioned.Interface
+	kubeClient     kubernetes.Interface
+	options        *options.CoredumpControllerOptions
+	recorder       record.EventRecorder
+
+	informerFactory informers.SharedInformerFactory
+	quotaLister     listers.CoredumpQuotaLister
+	coredumpsSynced cache.InformerSynced
+	quotasSynced    cache.InformerSynced
+
+	// queue of namespaces to sync. A namespace is never synced by two
+	// workers at the same time, so admission decisions and quota usage of a
+	// namespace are consistent.
+	queue workqueue.RateLimitingInterface
+
+	// namespaces queued by the quota reconciler, whose quotas are checked
+	// for drift when they're synced.
+	reconcileLock     sync.Mutex
+	reconcileRequests sets.String
 }
 
-func NewCoredumpController(kubeConfig string) (*CoredumpController, error) {
-	// Create the client config. Use kubeconfig if given, otherwise assume in-cluster.
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
-	if err != nil {
-		return nil, err
+// NewCoredumpController returns a controller of coredumps. Every resync
+// period, all namespaces with coredumps or quotas are synced again, so that
+// failed updates are retried.
+func NewCoredumpController(client versioned.Interface, kubeClient kubernetes.Interface, options *options.CoredumpControllerOptions) *CoredumpController {
+	factory := informers.NewSharedInformerFactory(client, options.ResyncPeriod)
+	coredumpInformer := factory.Coredump().V1alpha1().Coredumps()
+	quotaInformer := factory.Coredump().V1alpha1().CoredumpQuotas()
+
+	c := &CoredumpController{
+		CoredumpClient:    client,
+		kubeClient:        kubeClient,
+		options:           options,
+		recorder:          newEventRecorder(kubeClient),
+		informerFactory:   factory,
+		quotaLister:       quotaInformer.Lister(),
+		reconcileRequests: sets.NewString(),
+		coredumpsSynced:   coredumpInformer.Informer().HasSynced,
+		quotasSynced:      quotaInformer.Informer().HasSynced,
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "coredumps"),
 	}
 
-	// make a new config for our extension's API group, using the first config as a baseline
-	exampleClient, exampleScheme, err := newCoredumpClient(config)
-	if err != nil {
-		return nil, err
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		// coredumps are created without status, which is set to Created by
+		// an update of the status subresource.
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueue(newObj)
+		},
+		// usage of quotas is recomputed without the deleted coredump.
+		DeleteFunc: c.enqueue,
 	}
+	coredumpInformer.Informer().AddEventHandler(handler)
+	quotaInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		// status updates of quotas are made by the controller itself.
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldQuota := oldObj.(*coredump.CoredumpQuota)
+			newQuota := newObj.(*coredump.CoredumpQuota)
+			if !reflect.DeepEqual(oldQuota.Spec, newQuota.Spec) || oldQuota.ResourceVersion == newQuota.ResourceVersion {
+				c.enqueue(newObj)
+			}
+		},
+	})
+	return c
+}
 
-	controller := &CoredumpController{
-		CoredumpClient: exampleClient,
-		CoredumpScheme: exampleScheme,
+// Run starts workers syncing namespaces, and blocks until ctx is done.
+func (c *CoredumpController) Run(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Info("Starting coredump controller")
+	defer glog.Info("Shutting down coredump controller")
+
+	c.informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.coredumpsSynced, c.quotasSynced) {
+		return fmt.Errorf("failed to wait for coredump caches to sync")
 	}
-	return controller, nil
-}
 
-func newCoredumpClient(cfg *rest.Config) (*rest.RESTClient, *runtime.Scheme, error) {
-	scheme := runtime.NewScheme()
-	if err := coredump.AddToScheme(scheme); err != nil {
-		return nil, nil, err
+	for i := 0; i < c.options.Workers; i++ {
+		go wait.Until(c.runWorker, time.Second, ctx.Done())
 	}
 
-	config := *cfg
-	config.GroupVersion = &coredump.SchemeGroupVersion
-	config.APIPath = "/apis"
-	config.ContentType = runtime.ContentTypeJSON
-	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: serializer.NewCodecFactory(scheme)}
+	// Delete expired Coredump objects
+	go c.runGC(ctx)
+	// Correct drifted usage of quotas
+	go c.runQuotaReconciler(ctx)
 
-	client, err := rest.RESTClientFor(&config)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// enqueue adds the namespace of a coredump or quota to the queue.
+func (c *CoredumpController) enqueue(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		namespace, _, err := cache.SplitMetaNamespaceKey(tombstone.Key)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("couldn't get namespace from tombstone %#v: %v", obj, err))
+			return
+		}
+		c.queue.Add(namespace)
+		return
+	}
+	accessor, err := meta.Accessor(obj)
 	if err != nil {
-		return nil, nil, err
+		utilruntime.HandleError(fmt.Errorf("couldn't get namespace for object %+v: %v", obj, err))
+		return
 	}
-
-	return client, scheme, nil
+	c.queue.Add(accessor.GetNamespace())
 }
 
-// Run starts an Coredump resource controller
-func (c *CoredumpController) Run(ctx context.Context) error {
-	fmt.Print("Watch Coredump objects\n")
+func (c *CoredumpController) runWorker() {
+	for c.processNextWorkItem() {
+	}
+}
 
-	// Watch Coredump objects
-	_, err := c.watchCoredumps(ctx)
-	if err != nil {
-		fmt.Printf("Failed to register watch for Coredump resource: %v\n", err)
-		return err
+func (c *CoredumpController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
 	}
+	defer c.queue.Done(key)
 
-	<-ctx.Done()
-	return ctx.Err()
+	err := c.syncNamespace(key.(string))
+	c.handleErr(err, key)
+	return true
 }
 
-func (c *CoredumpController) watchCoredumps(ctx context.Context) (cache.Controller, error) {
-	source := cache.NewListWatchFromClient(
-		c.CoredumpClient,
-		coredump.CoredumpResourcePlural,
-		apiv1.NamespaceAll,
-		fields.Everything())
-
-	_, controller := cache.NewInformer(
-		source,
-
-		// The object type.
-		&coredump.Coredump{},
-
-		// resyncPeriod
-		// Every resyncPeriod, all resources in the cache will retrigger events.
-		// Set to 0 to disable the resync.
-		0,
-
-		// Your custom resource event handlers.
-		cache.ResourceEventHandlerFuncs{
-			AddFunc:    c.onAdd,
-			UpdateFunc: c.onUpdate,
-			DeleteFunc: c.onDelete,
-		})
-
-	go controller.Run(ctx.Done())
-	return controller, nil
-}
+func (c *CoredumpController) handleErr(err error, key interface{}) {
+	if err == nil {
+		c.queue.Forget(key)
+		return
+	}
 
-func (c *CoredumpController) onAdd(obj interface{}) {
-	example := obj.(*coredump.Coredump)
-	if example.Status.State != coredump.CoredumpStateCreated {
+	if c.queue.NumRequeues(key) < maxRetries {
+		glog.V(2).Infof("Error syncing coredumps of namespace %v: %v", key, err)
+		c.queue.AddRateLimited(key)
 		return
 	}
-	// NEVER modify objects from the store. It's a read-only, local cache.
-	// You can use DeepCopy() to make a deep copy of original object and modify this copy
-	// Or create a copy manually for better performance
-	exampleCopy := example.DeepCopy()
-	message := ""
-	fmt.Printf("[CONTROLLER] OnAdd %s\n", example.ObjectMeta.SelfLink)
 
-	quotaList := coredump.CoredumpQuotaList{}
-	err := c.CoredumpClient.Get().Namespace(example.ObjectMeta.Namespace).Resource(coredump.CoredumpQuotaResourcePlural).Do().Into(&quotaList)
+	utilruntime.HandleError(fmt.Errorf("dropping namespace %q out of the queue: %v", key, err))
+	c.queue.Forget(key)
+}
+
+// syncNamespace admits the Created coredumps of namespace in the order of
+// dump time, and sets the usage of quotas to the coredumps consuming quota
+// in their scope. Coredumps and quotas are read from the apiserver rather
+// than the cache, and updated with the resourceVersion read, so a conflict
+// fails the sync and it's retried from the beginning. Syncing a namespace
+// more than once, e.g. on resync, is safe.
+func (c *CoredumpController) syncNamespace(namespace string) error {
+	startTime := time.Now()
+	defer func() {
+		glog.V(4).Infof("Finished syncing coredumps of namespace %q (%v)", namespace, time.Since(startTime))
+	}()
+	checkDrift := c.takeReconcileRequest(namespace)
+
+	coredumpList, err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(namespace).List(metav1.ListOptions{})
 	if err != nil {
-		fmt.Printf("Error %v\n", err)
-		return
+		return err
+	}
+	quotaList, err := c.CoredumpClient.CoredumpV1alpha1().CoredumpQuotas(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return err
 	}
 
-	exceed := false
-	// check whether we exceed any quota
-	for _, q := range quotaList.Items {
-		totalSize := (*example.Spec.Size).DeepCopy()
-		if (q.Status != coredump.QuotaStatus{}) {
-			totalSize.Add(*q.Status.Used)
-		}
-		if totalSize.Cmp(*q.Spec.Hard) > 0 {
-			exceed = true
-			message = fmt.Sprintf("Quota exceed, required %s, but %s has only %s", totalSize.String(), q.ObjectMeta.Name, q.Spec.Hard.String())
+	var usages []*quotaUsage
+	for i := range quotaList.Items {
+		u, err := newQuotaUsage(&quotaList.Items[i], coredumpList.Items)
+		if err != nil {
+			return err
 		}
+		usages = append(usages, u)
 	}
-
-	if exceed {
-		exampleCopy.Status = coredump.CoredumpStatus{
-			State:   coredump.CoredumpStateDenied,
-			Message: message,
+	if checkDrift {
+		c.reportDrift(usages)
+	}
+	// saved coredumps may be evicted for quotas in EvictOldest policy, and
+	// the space of coredumps being deleted is freed soon.
+	var created, saved, deleting []*coredump.Coredump
+	for i := range coredumpList.Items {
+		cd := &coredumpList.Items[i]
+		switch {
+		case cd.Status.State == coredump.CoredumpStateCreated:
+			created = append(created, cd)
+		case cd.ObjectMeta.DeletionTimestamp != nil:
+			if consumesQuota(cd) && hasFile(cd) {
+				deleting = append(deleting, cd)
+			}
+		case cd.Status.State == coredump.CoredumpStateProcessed:
+			saved = append(saved, cd)
 		}
-		c.saveStatus(exampleCopy)
-		return
 	}
-
-	// set quota
-	for _, q := range quotaList.Items {
-		qq := q.DeepCopy()
-		qq.Status.Hard = q.Spec.Hard
-		totalSize := (*example.Spec.Size).DeepCopy()
-		if (q.Status != coredump.QuotaStatus{}) {
-			totalSize.Add(*qq.Status.Used)
+	if len(created) > 0 {
+		sort.Sort(byDumpTime(created))
+		sort.Sort(byDumpTime(saved))
+		policyList, err := c.CoredumpClient.CoredumpV1alpha1().CoredumpPolicies(namespace).List(metav1.ListOptions{})
+		if apierrors.IsNotFound(err) {
+			// CoredumpPolicy is not defined in the cluster.
+			policyList, err = &coredump.CoredumpPolicyList{}, nil
 		}
-		qq.Status.Used = &totalSize
-
-		err = c.CoredumpClient.Put().
-			Name(qq.ObjectMeta.Name).
-			Namespace(qq.ObjectMeta.Namespace).
-			Resource(coredump.CoredumpQuotaResourcePlural).
-			Body(qq).
-			Do().
-			Error()
-
 		if err != nil {
-			fmt.Printf("%v\n", err)
+			return err
+		}
+		for _, cd := range created {
+			allowed, err := c.admit(cd, policyList.Items, usages, &saved, deleting)
+			if err != nil {
+				return err
+			}
+			if allowed {
+				for _, u := range usages {
+					u.add(cd)
+				}
+			}
 		}
 	}
 
-	exampleCopy.Status = coredump.CoredumpStatus{
-		State:   coredump.CoredumpStateStateAllowed,
-		Message: "Ready for saving to  persistent volume",
-	}
-	c.saveStatus(exampleCopy)
+	return c.updateQuotaUsage(usages)
 }
 
-func (c *CoredumpController) saveStatus(example *coredump.Coredump) {
-	err := c.CoredumpClient.Put().
-		Name(example.ObjectMeta.Name).
-		Namespace(example.ObjectMeta.Namespace).
-		Resource(coredump.CoredumpResourcePlural).
-		Body(example).
-		Do().
-		Error()
-
+// admit checks the policies and quotas of a Created coredump, and updates
+// its state. usages are the usage of quotas before the coredump, saved are
+// the saved coredumps which may be evicted, oldest first, and deleting are
+// the coredumps being deleted whose files are still counted in usages.
+func (c *CoredumpController) admit(cd *coredump.Coredump, policies []coredump.CoredumpPolicy, usages []*quotaUsage, saved *[]*coredump.Coredump, deleting []*coredump.Coredump) (bool, error) {
+	result, err := policy.Evaluate(policies, cd.Spec.PodLabels, cd.Spec.ContainerName, cd.Spec.Filename)
 	if err != nil {
-		fmt.Printf("ERROR updating status: %v\n", err)
-	} else {
-		fmt.Printf("UPDATED status: %#v\n", example)
+		return false, err
+	}
+	if !result.Allowed {
+		return false, c.updateState(cd, coredump.CoredumpStateDenied, result.Message)
+	}
+	if ok, reason := result.AllowSize(cd.Spec.Size); !ok {
+		return false, c.updateState(cd, coredump.CoredumpStateDenied, reason)
+	}
+	if result.Retention != nil {
+		expireTime := cd.Spec.Time.Add(*result.Retention).Format(time.RFC3339)
+		if cd.ObjectMeta.Annotations[coredump.ExpireTimeAnnotation] != expireTime {
+			if cd.ObjectMeta.Annotations == nil {
+				cd.ObjectMeta.Annotations = map[string]string{}
+			}
+			cd.ObjectMeta.Annotations[coredump.ExpireTimeAnnotation] = expireTime
+			// status is a subresource, the annotation is saved separately.
+			updated, err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).Update(cd)
+			if err != nil {
+				return false, err
+			}
+			*cd = *updated
+		}
 	}
-}
 
-func (c *CoredumpController) onUpdate(oldObj, newObj interface{}) {
-	oldCoredump := oldObj.(*coredump.Coredump)
-	newCoredump := newObj.(*coredump.Coredump)
-	fmt.Printf("[CONTROLLER] OnUpdate oldObj: %s\n", oldCoredump.ObjectMeta.SelfLink)
-	fmt.Printf("[CONTROLLER] OnUpdate newObj: %s\n", newCoredump.ObjectMeta.SelfLink)
+	// check whether we exceed any quota
+	victims, limit := planEviction(cd, usages, saved, deleting)
+	if limit != nil {
+		return false, c.updateState(cd, coredump.CoredumpStateDenied, limit.message)
+	}
+	message := "Ready for saving to  persistent volume"
+	if len(victims) > 0 {
+		if err := c.evict(victims); err != nil {
+			return false, err
+		}
+		names := make([]string, 0, len(victims))
+		for _, victim := range victims {
+			names = append(names, victim.ObjectMeta.Name)
+		}
+		message = fmt.Sprintf("%s, evicted %s to fit in quota", message, strings.Join(names, ", "))
+	}
+
+	if err := c.updateState(cd, coredump.CoredumpStateStateAllowed, message); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (c *CoredumpController) onDelete(obj interface{}) {
-	example := obj.(*coredump.Coredump)
-	fmt.Printf("[CONTROLLER] OnDelete %s\n", example.ObjectMeta.SelfLink)
-	if example.Status.State != coredump.CoredumpStateProcessed &&
-		example.Status.State != coredump.CoredumpStateFailed &&
-		example.Status.State != coredump.CoredumpStateStateAllowed {
-		return
+func (c *CoredumpController) updateState(cd *coredump.Coredump, state coredump.CoredumpState, message string) error {
+	cd.Status = coredump.CoredumpStatus{
+		State:   state,
+		Message: message,
 	}
-	// free quota for deleted coredump file
-	quotaList := coredump.CoredumpQuotaList{}
-	err := c.CoredumpClient.Get().Namespace(example.ObjectMeta.Namespace).Resource(coredump.CoredumpQuotaResourcePlural).Do().Into(&quotaList)
-	if err != nil {
-		fmt.Printf("Error %v\n", err)
-		return
+	if _, err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).UpdateStatus(cd); err != nil {
+		return err
 	}
+	glog.Infof("Updated coredump %s/%s to state %s: %s", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, state, message)
+	return nil
+}
 
-	for _, q := range quotaList.Items {
-		qq := q.DeepCopy()
-		qq.Status.Hard = q.Spec.Hard
-		totalSize := (*qq.Status.Used).DeepCopy()
-		totalSize.Sub(*example.Spec.Size)
-		qq.Status.Used = &totalSize
-
-		err = c.CoredumpClient.Put().
-			Name(qq.ObjectMeta.Name).
-			Namespace(qq.ObjectMeta.Namespace).
-			Resource(coredump.CoredumpQuotaResourcePlural).
-			Body(qq).
-			Do().
-			Error()
+// byDumpTime sorts coredumps by dump time, and then by name.
+type byDumpTime []*coredump.Coredump
 
-		if err != nil {
-			fmt.Printf("%v\n", err)
-		}
+func (s byDumpTime) Len() int      { return len(s) }
+func (s byDumpTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDumpTime) Less(i, j int) bool {
+	if !s[i].Spec.Time.Equal(&s[j].Spec.Time) {
+		return s[i].Spec.Time.Before(&s[j].Spec.Time)
 	}
+	return s[i].ObjectMeta.Name < s[j].ObjectMeta.Name
 }
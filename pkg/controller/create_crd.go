@@ -31,5 +31,11 @@ func CreateCustomResourceDefinition(kubeConfig string) error {
 	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return err
 	}
-	return nil
+	_, err = apiextensionsClient.CreateCoredumpPolicyDefinition()
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	// CRDs created by former versions have no validation, status subresource
+	// or printer columns.
+	return apiextensionsClient.UpdateDefinitions()
 }
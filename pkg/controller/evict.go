@@ -0,0 +1,114 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+// planEviction returns the saved coredumps to evict, so that cd fits in all
+// quotas. Coredumps are evicted oldest first, only for quotas in EvictOldest
+// policy. Coredumps being deleted, e.g. evicted by a previous sync, are
+// counted in usages until their files are removed, but their space is
+// considered free, so they don't make more coredumps evicted. If cd can't
+// fit, the limit exceeded is returned and usages are left unchanged.
+// Otherwise usages are updated without the evicted coredumps, and they're
+// removed from candidates.
+func planEviction(cd *coredump.Coredump, usages []*quotaUsage, candidates *[]*coredump.Coredump, deleting []*coredump.Coredump) ([]*coredump.Coredump, *exceededLimit) {
+	for _, d := range deleting {
+		for _, u := range usages {
+			u.remove(d)
+		}
+	}
+	defer func() {
+		for _, d := range deleting {
+			for _, u := range usages {
+				u.add(d)
+			}
+		}
+	}()
+
+	var victims []*coredump.Coredump
+	evicted := map[*coredump.Coredump]bool{}
+	restore := func() {
+		for _, victim := range victims {
+			for _, u := range usages {
+				u.add(victim)
+			}
+		}
+	}
+
+	for _, u := range usages {
+		for {
+			limit := u.check(cd)
+			if limit == nil {
+				break
+			}
+			if u.quota.Spec.ExceedPolicy != coredump.QuotaExceedEvictOldest {
+				restore()
+				return nil, limit
+			}
+			var victim *coredump.Coredump
+			for _, candidate := range *candidates {
+				if !evicted[candidate] && limit.reducedBy(candidate) {
+					victim = candidate
+					break
+				}
+			}
+			if victim == nil {
+				restore()
+				return nil, limit
+			}
+			victims = append(victims, victim)
+			evicted[victim] = true
+			for _, u := range usages {
+				u.remove(victim)
+			}
+		}
+	}
+
+	if len(victims) > 0 {
+		var remaining []*coredump.Coredump
+		for _, candidate := range *candidates {
+			if !evicted[candidate] {
+				remaining = append(remaining, candidate)
+			}
+		}
+		*candidates = remaining
+	}
+	return victims, nil
+}
+
+// evict deletes the coredumps, their files in the persistent volume are
+// removed by coredump-saver.
+func (c *CoredumpController) evict(victims []*coredump.Coredump) error {
+	for _, victim := range victims {
+		uid := victim.ObjectMeta.UID
+		err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(victim.ObjectMeta.Namespace).Delete(victim.ObjectMeta.Name, &metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		glog.Infof("Evicted coredump %s/%s", victim.ObjectMeta.Namespace, victim.ObjectMeta.Name)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/client/clientset/versioned/fake"
+)
+
+func names(coredumps []*coredump.Coredump) []string {
+	var names []string
+	for _, cd := range coredumps {
+		names = append(names, cd.ObjectMeta.Name)
+	}
+	return names
+}
+
+func TestPlanEviction(t *testing.T) {
+	// saved coredumps, listed newest first to check they're evicted oldest
+	// first once sorted.
+	saved := []coredump.Coredump{
+		newCoredump("b-2", "b", "c1", "1Mi", 4, coredump.CoredumpStateProcessed),
+		newCoredump("a-2", "a", "c2", "2Mi", 3, coredump.CoredumpStateProcessed),
+		newCoredump("a-1", "a", "c1", "3Mi", 2, coredump.CoredumpStateProcessed),
+		newCoredump("b-1", "b", "c1", "4Mi", 1, coredump.CoredumpStateProcessed),
+	}
+	evictOldest := func(spec coredump.QuotaSpec) coredump.QuotaSpec {
+		spec.ExceedPolicy = coredump.QuotaExceedEvictOldest
+		return spec
+	}
+	for _, tc := range []struct {
+		name   string
+		quotas []coredump.QuotaSpec
+		cd     coredump.Coredump
+		// wantVictims are the names of coredumps evicted, oldest first.
+		wantVictims []string
+		// wantLimit is a substring of the message of the limit exceeded,
+		// empty if cd fits.
+		wantLimit string
+	}{
+		{
+			name:   "fits without eviction",
+			quotas: []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{Hard: quantity("20Mi")})},
+			cd:     newCoredump("new", "a", "c1", "10Mi", 5, coredump.CoredumpStateCreated),
+		},
+		{
+			name:      "deny policy",
+			quotas:    []coredump.QuotaSpec{{Hard: quantity("10Mi")}},
+			cd:        newCoredump("new", "a", "c1", "1Mi", 5, coredump.CoredumpStateCreated),
+			wantLimit: "has only 10Mi",
+		},
+		{
+			name:        "evict the oldest to fit exactly",
+			quotas:      []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")})},
+			cd:          newCoredump("new", "a", "c1", "4Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"b-1"},
+		},
+		{
+			name:        "evict until it fits",
+			quotas:      []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")})},
+			cd:          newCoredump("new", "a", "c1", "5Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"b-1", "a-1"},
+		},
+		{
+			name:      "larger than the quota",
+			quotas:    []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")})},
+			cd:        newCoredump("new", "a", "c1", "11Mi", 5, coredump.CoredumpStateCreated),
+			wantLimit: "has only 10Mi",
+		},
+		{
+			name:        "max count",
+			quotas:      []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{MaxCount: count(3)})},
+			cd:          newCoredump("new", "a", "c1", "1Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"b-1", "a-1"},
+		},
+		{
+			name:        "per pod evicts the same pod",
+			quotas:      []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{PerPodHard: quantity("6Mi")})},
+			cd:          newCoredump("new", "a", "c3", "2Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"a-1"},
+		},
+		{
+			name:      "per pod larger than the limit",
+			quotas:    []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{PerPodHard: quantity("6Mi")})},
+			cd:        newCoredump("new", "a", "c3", "7Mi", 5, coredump.CoredumpStateCreated),
+			wantLimit: "for each pod",
+		},
+		{
+			name:        "per container evicts the same container",
+			quotas:      []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{PerContainerMaxCount: count(1)})},
+			cd:          newCoredump("new", "a", "c2", "1Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"a-2"},
+		},
+		{
+			name: "selector evicts pods in scope",
+			quotas: []coredump.QuotaSpec{evictOldest(coredump.QuotaSpec{
+				Hard:     quantity("6Mi"),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+			})},
+			cd:          newCoredump("new", "a", "c1", "2Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"a-1"},
+		},
+		{
+			name: "deny quota after evict quota",
+			quotas: []coredump.QuotaSpec{
+				evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")}),
+				{MaxCount: count(3)},
+			},
+			cd:        newCoredump("new", "a", "c1", "4Mi", 5, coredump.CoredumpStateCreated),
+			wantLimit: "allows only 3 coredumps",
+		},
+		{
+			name: "eviction for one quota fits another",
+			quotas: []coredump.QuotaSpec{
+				evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")}),
+				{PerPodHard: quantity("8Mi")},
+			},
+			cd:          newCoredump("new", "b", "c1", "4Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"b-1"},
+		},
+		{
+			name: "evicted once for two quotas",
+			quotas: []coredump.QuotaSpec{
+				evictOldest(coredump.QuotaSpec{Hard: quantity("10Mi")}),
+				evictOldest(coredump.QuotaSpec{MaxCount: count(4)}),
+			},
+			cd:          newCoredump("new", "a", "c1", "4Mi", 5, coredump.CoredumpStateCreated),
+			wantVictims: []string{"b-1"},
+		},
+	} {
+		var usages []*quotaUsage
+		for _, spec := range tc.quotas {
+			u, err := newQuotaUsage(newQuota(spec), saved)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			usages = append(usages, u)
+		}
+		var candidates []*coredump.Coredump
+		for i := range saved {
+			candidates = append(candidates, &saved[i])
+		}
+		sort.Sort(byDumpTime(candidates))
+		before := make([]coredump.QuotaStatus, len(usages))
+		for i, u := range usages {
+			before[i] = u.status()
+		}
+
+		victims, limit := planEviction(&tc.cd, usages, &candidates, nil)
+		if tc.wantLimit != "" {
+			if limit == nil || !strings.Contains(limit.message, tc.wantLimit) {
+				t.Errorf("%s: limit = %+v, want %q", tc.name, limit, tc.wantLimit)
+			}
+			if victims != nil || len(candidates) != len(saved) {
+				t.Errorf("%s: evicted %v and left %d candidates, but cd doesn't fit", tc.name, names(victims), len(candidates))
+			}
+			for i, u := range usages {
+				if after := u.status(); !quotaStatusEqual(&before[i], &after) {
+					t.Errorf("%s: usage of quota %d changed from %+v to %+v", tc.name, i, before[i], after)
+				}
+			}
+			continue
+		}
+		if limit != nil {
+			t.Errorf("%s: exceeded %q, want fit", tc.name, limit.message)
+			continue
+		}
+		if got := names(victims); !reflect.DeepEqual(got, tc.wantVictims) {
+			t.Errorf("%s: evicted %v, want %v", tc.name, got, tc.wantVictims)
+		}
+		if len(candidates) != len(saved)-len(tc.wantVictims) {
+			t.Errorf("%s: %d candidates left, want %d", tc.name, len(candidates), len(saved)-len(tc.wantVictims))
+		}
+		for _, u := range usages {
+			if exceeded := u.check(&tc.cd); exceeded != nil {
+				t.Errorf("%s: exceeded %q after eviction", tc.name, exceeded.message)
+			}
+		}
+	}
+}
+
+func TestSyncNamespaceWhileVictimsAreDeleted(t *testing.T) {
+	quota := newQuota(coredump.QuotaSpec{Hard: quantity("10Mi"), ExceedPolicy: coredump.QuotaExceedEvictOldest})
+	b1 := newCoredump("b-1", "b", "c1", "4Mi", 1, coredump.CoredumpStateProcessed)
+	b1.ObjectMeta.Finalizers = []string{coredump.FileCleanupFinalizer}
+	a1 := newCoredump("a-1", "a", "c1", "3Mi", 2, coredump.CoredumpStateProcessed)
+	first := newCoredump("first", "a", "c1", "4Mi", 3, coredump.CoredumpStateCreated)
+	client := fake.NewSimpleClientset(quota, &b1, &a1, &first)
+	c := &CoredumpController{CoredumpClient: client, reconcileRequests: sets.NewString()}
+	coredumps := client.CoredumpV1alpha1().Coredumps("default")
+
+	if err := c.syncNamespace("default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := coredumps.Get("b-1", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("b-1 is not evicted for the first coredump: %v", err)
+	}
+	// the fake clientset deletes b-1 at once, while the apiserver keeps it
+	// until coredump-saver removes its file and the finalizer.
+	now := metav1.Now()
+	b1.ObjectMeta.DeletionTimestamp = &now
+	second := newCoredump("second", "a", "c2", "3Mi", 4, coredump.CoredumpStateCreated)
+	for _, cd := range []*coredump.Coredump{&b1, &second} {
+		if _, err := coredumps.Create(cd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.syncNamespace("default"); err != nil {
+		t.Fatal(err)
+	}
+	// a-1, first and second fit in 10Mi once b-1 is removed.
+	for name, want := range map[string]coredump.CoredumpState{
+		"a-1":    coredump.CoredumpStateProcessed,
+		"first":  coredump.CoredumpStateStateAllowed,
+		"second": coredump.CoredumpStateStateAllowed,
+	} {
+		cd, err := coredumps.Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if cd.Status.State != want {
+			t.Errorf("%s: state = %s (%s), want %s", name, cd.Status.State, cd.Status.Message, want)
+		}
+	}
+	// b-1 is counted until its file is removed.
+	q, err := client.CoredumpV1alpha1().CoredumpQuotas("default").Get("quota", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := resource.MustParse("14Mi"); q.Status.Used == nil || q.Status.Used.Cmp(want) != 0 || q.Status.Count != 4 {
+		t.Errorf("usage = %v in %d coredumps, want %s in 4 coredumps", q.Status.Used, q.Status.Count, want.String())
+	}
+}
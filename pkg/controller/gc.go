@@ -0,0 +1,226 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+// gcPeriod is the period to check expired Coredump objects.
+const gcPeriod = time.Minute
+
+// runGC deletes expired Coredump objects periodically until ctx is done.
+func (c *CoredumpController) runGC(ctx context.Context) {
+	wait.Until(c.deleteExpiredCoredumps, gcPeriod, ctx.Done())
+}
+
+// deleteExpiredCoredumps deletes the Coredump objects whose retention is
+// over. Files of the coredumps are removed by coredump-saver before the
+// objects disappear, see coredump.FileCleanupFinalizer.
+func (c *CoredumpController) deleteExpiredCoredumps() {
+	coredumpList, err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		glog.Errorf("Failed to list coredumps: %v", err)
+		return
+	}
+	quotaList, err := c.CoredumpClient.CoredumpV1alpha1().CoredumpQuotas(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		// CoredumpQuota is not defined in the cluster.
+		quotaList, err = &coredump.CoredumpQuotaList{}, nil
+	}
+	if err != nil {
+		glog.Errorf("Failed to list coredump quotas: %v", err)
+		return
+	}
+	r := &retentions{
+		controller: c,
+		quotas:     map[string][]coredump.CoredumpQuota{},
+		namespaces: map[string]*time.Duration{},
+	}
+	for _, q := range quotaList.Items {
+		r.quotas[q.ObjectMeta.Namespace] = append(r.quotas[q.ObjectMeta.Namespace], q)
+	}
+
+	now := time.Now()
+	nodes := map[string]bool{}
+	for i := range coredumpList.Items {
+		cd := &coredumpList.Items[i]
+		if cd.ObjectMeta.DeletionTimestamp != nil {
+			c.releaseFromMissingNode(cd, nodes)
+			continue
+		}
+		expireTime, err := r.expireTime(cd)
+		if err != nil {
+			glog.Errorf("Failed to get expire time of coredump %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+			continue
+		}
+		if expireTime == nil || now.Before(*expireTime) {
+			continue
+		}
+		uid := cd.ObjectMeta.UID
+		err = c.CoredumpClient.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).Delete(cd.ObjectMeta.Name, &metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			glog.Errorf("Failed to delete expired coredump %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+		} else {
+			glog.Infof("Deleted expired coredump %s/%s", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name)
+		}
+	}
+}
+
+// releaseFromMissingNode removes the file cleanup finalizer of a coredump
+// being deleted, if the node where its file is cached no longer exists. No
+// coredump-saver would remove the finalizer, and the file is gone with the
+// node. nodes caches whether each node exists in a GC run.
+func (c *CoredumpController) releaseFromMissingNode(cd *coredump.Coredump, nodes map[string]bool) {
+	if !hasFile(cd) {
+		return
+	}
+	node := cd.ObjectMeta.Labels[coredump.CoredumpNodeLabel]
+	if node == "" {
+		return
+	}
+	exists, ok := nodes[node]
+	if !ok {
+		_, err := c.kubeClient.CoreV1().Nodes().Get(node, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			glog.Errorf("Failed to get node %s: %v", node, err)
+			return
+		}
+		exists = err == nil
+		nodes[node] = exists
+	}
+	if exists {
+		return
+	}
+	cdCopy := cd.DeepCopy()
+	var finalizers []string
+	for _, f := range cdCopy.ObjectMeta.Finalizers {
+		if f != coredump.FileCleanupFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	cdCopy.ObjectMeta.Finalizers = finalizers
+	if _, err := c.CoredumpClient.CoredumpV1alpha1().Coredumps(cd.ObjectMeta.Namespace).Update(cdCopy); err != nil {
+		glog.Errorf("Failed to remove finalizer of coredump %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+		return
+	}
+	if cd.Status.State == coredump.CoredumpStateProcessed {
+		glog.Warningf("Removed finalizer of coredump %s/%s, node %s no longer exists, its file in %s is left", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, node, cd.Spec.Volume)
+		return
+	}
+	glog.Infof("Removed finalizer of coredump %s/%s, node %s no longer exists", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, node)
+}
+
+// retentions looks up the retention of coredumps in a GC run, namespaces are
+// only read once.
+type retentions struct {
+	controller *CoredumpController
+	// quotas of each namespace
+	quotas map[string][]coredump.CoredumpQuota
+	// retention annotation of each namespace read, nil if it's not set.
+	namespaces map[string]*time.Duration
+}
+
+// expireTime returns the time after which cd is deleted, nil if it's kept
+// forever. The retention is the first one set in:
+// * the expire time annotation set from coredump policies
+// * the shortest retention of coredump quotas limiting cd
+// * the retention annotation of the namespace
+// * the default retention of the controller
+func (r *retentions) expireTime(cd *coredump.Coredump) (*time.Time, error) {
+	if value, ok := cd.ObjectMeta.Annotations[coredump.ExpireTimeAnnotation]; ok {
+		expireTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", coredump.ExpireTimeAnnotation, err)
+		}
+		return &expireTime, nil
+	}
+
+	retention, err := r.quotaRetention(cd)
+	if err != nil {
+		return nil, err
+	}
+	if retention == nil {
+		retention, err = r.namespaceRetention(cd.ObjectMeta.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if retention == nil && r.controller.options.DefaultRetention > 0 {
+		retention = &r.controller.options.DefaultRetention
+	}
+	if retention == nil {
+		return nil, nil
+	}
+	expireTime := cd.Spec.Time.Add(*retention)
+	return &expireTime, nil
+}
+
+// quotaRetention returns the shortest retention of quotas limiting cd.
+func (r *retentions) quotaRetention(cd *coredump.Coredump) (*time.Duration, error) {
+	var retention *time.Duration
+	for i := range r.quotas[cd.ObjectMeta.Namespace] {
+		q := &r.quotas[cd.ObjectMeta.Namespace][i]
+		if q.Spec.Retention == nil {
+			continue
+		}
+		selector, err := quotaSelector(q)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(cd.Spec.PodLabels)) {
+			continue
+		}
+		if retention == nil || q.Spec.Retention.Duration < *retention {
+			retention = &q.Spec.Retention.Duration
+		}
+	}
+	return retention, nil
+}
+
+// namespaceRetention returns the retention annotation of the namespace.
+func (r *retentions) namespaceRetention(name string) (*time.Duration, error) {
+	if retention, ok := r.namespaces[name]; ok {
+		return retention, nil
+	}
+	ns, err := r.controller.kubeClient.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var retention *time.Duration
+	if value, ok := ns.ObjectMeta.Annotations[coredump.RetentionAnnotation]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of namespace %s: %v", coredump.RetentionAnnotation, name, err)
+		}
+		retention = &d
+	}
+	r.namespaces[name] = retention
+	return retention, nil
+}
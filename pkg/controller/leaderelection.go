@@ -0,0 +1,71 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"os"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"k8s.io/coredump-detector/cmd/options"
+)
+
+// leaderElectionLockName is the name of the ConfigMap used as the lock of
+// leader election.
+const leaderElectionLockName = controllerName
+
+// RunWithLeaderElection blocks until this replica becomes the leader, and
+// then calls run. It exits the process when the leadership is lost, so that
+// two replicas never process coredumps at the same time.
+func RunWithLeaderElection(kubeClient kubernetes.Interface, cco *options.CoredumpControllerOptions, run func(stop <-chan struct{})) {
+	id, err := os.Hostname()
+	if err != nil {
+		glog.Fatalf("Failed to get hostname: %v", err)
+	}
+	// add a uniquifier so that two processes on the same host don't
+	// accidentally both become active
+	id = id + "_" + string(uuid.NewUUID())
+
+	lock, err := resourcelock.New(resourcelock.ConfigMapsResourceLock,
+		cco.LeaderElectNamespace,
+		leaderElectionLockName,
+		kubeClient.CoreV1(),
+		resourcelock.ResourceLockConfig{
+			Identity:      id,
+			EventRecorder: newEventRecorder(kubeClient),
+		})
+	if err != nil {
+		glog.Fatalf("Failed to create resource lock: %v", err)
+	}
+
+	leaderelection.RunOrDie(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: cco.LeaderElectLeaseDuration,
+		RenewDeadline: cco.LeaderElectRenewDeadline,
+		RetryPeriod:   cco.LeaderElectRetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: run,
+			OnStoppedLeading: func() {
+				glog.Fatalf("Leader election lost")
+			},
+		},
+	})
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+// consumesQuota returns whether cd is counted in the usage of quotas.
+// Allowed coredumps are being saved, and coredumps failed to save are still in
+// the host cache, unless they're discarded because of node disk pressure.
+func consumesQuota(cd *coredump.Coredump) bool {
+	switch cd.Status.State {
+	case coredump.CoredumpStateStateAllowed, coredump.CoredumpStateProcessed:
+		return true
+	case coredump.CoredumpStateFailed:
+		return cd.Status.Reason != coredump.CoredumpReasonNodeDiskPressure
+	}
+	return false
+}
+
+func coredumpSize(cd *coredump.Coredump) resource.Quantity {
+	if cd.Spec.Size == nil {
+		return *resource.NewQuantity(0, resource.BinarySI)
+	}
+	return cd.Spec.Size.DeepCopy()
+}
+
+// quotaUsage is the usage of a quota, computed from the coredumps consuming
+// quota in its scope.
+type quotaUsage struct {
+	quota    *coredump.CoredumpQuota
+	selector labels.Selector
+
+	used  resource.Quantity
+	count int64
+	// pods is the usage of each pod by pod name.
+	pods map[string]*podUsage
+}
+
+type podUsage struct {
+	used resource.Quantity
+	// containers is the number of coredumps of each container.
+	containers map[string]int64
+}
+
+// quotaSelector returns the selector of pods whose coredumps are limited by
+// the quota.
+func quotaSelector(q *coredump.CoredumpQuota) (labels.Selector, error) {
+	if q.Spec.Selector == nil {
+		return labels.Everything(), nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(q.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of quota %s/%s: %v", q.ObjectMeta.Namespace, q.ObjectMeta.Name, err)
+	}
+	return selector, nil
+}
+
+// newQuotaUsage returns the usage of the quota. Coredumps being deleted are
+// counted until their files are removed, i.e. their file cleanup finalizer
+// is removed.
+func newQuotaUsage(q *coredump.CoredumpQuota, coredumps []coredump.Coredump) (*quotaUsage, error) {
+	selector, err := quotaSelector(q)
+	if err != nil {
+		return nil, err
+	}
+	u := &quotaUsage{
+		quota:    q,
+		selector: selector,
+		used:     *resource.NewQuantity(0, resource.BinarySI),
+		pods:     map[string]*podUsage{},
+	}
+	for i := range coredumps {
+		if consumesQuota(&coredumps[i]) && hasFile(&coredumps[i]) {
+			u.add(&coredumps[i])
+		}
+	}
+	return u, nil
+}
+
+// hasFile returns whether the file of cd may still exist. A coredump being
+// deleted keeps its file until coredump-saver removes the finalizer.
+func hasFile(cd *coredump.Coredump) bool {
+	if cd.ObjectMeta.DeletionTimestamp == nil {
+		return true
+	}
+	for _, f := range cd.ObjectMeta.Finalizers {
+		if f == coredump.FileCleanupFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope returns whether cd is limited by the quota.
+func (u *quotaUsage) inScope(cd *coredump.Coredump) bool {
+	return u.selector.Matches(labels.Set(cd.Spec.PodLabels))
+}
+
+// add counts cd in the usage if it's in the scope of the quota.
+func (u *quotaUsage) add(cd *coredump.Coredump) {
+	if !u.inScope(cd) {
+		return
+	}
+	size := coredumpSize(cd)
+	u.used.Add(size)
+	u.count++
+	pod, ok := u.pods[cd.Spec.Pod]
+	if !ok {
+		pod = &podUsage{
+			used:       *resource.NewQuantity(0, resource.BinarySI),
+			containers: map[string]int64{},
+		}
+		u.pods[cd.Spec.Pod] = pod
+	}
+	pod.used.Add(size)
+	pod.containers[cd.Spec.ContainerName]++
+}
+
+// remove subtracts cd counted by add from the usage.
+func (u *quotaUsage) remove(cd *coredump.Coredump) {
+	if !u.inScope(cd) {
+		return
+	}
+	size := coredumpSize(cd)
+	u.used.Sub(size)
+	u.count--
+	if pod, ok := u.pods[cd.Spec.Pod]; ok {
+		pod.used.Sub(size)
+		pod.containers[cd.Spec.ContainerName]--
+		if pod.containers[cd.Spec.ContainerName] <= 0 {
+			delete(pod.containers, cd.Spec.ContainerName)
+		}
+		if len(pod.containers) == 0 {
+			delete(u.pods, cd.Spec.Pod)
+		}
+	}
+}
+
+// exceededLimit is a limit of a quota exceeded by a coredump.
+type exceededLimit struct {
+	message string
+	// reducedBy returns whether evicting a coredump reduces the usage
+	// limited.
+	reducedBy func(cd *coredump.Coredump) bool
+}
+
+// check returns the limit of the quota exceeded if cd is added, or nil if
+// cd fits in the quota.
+func (u *quotaUsage) check(cd *coredump.Coredump) *exceededLimit {
+	if !u.inScope(cd) {
+		return nil
+	}
+	spec := u.quota.Spec
+	name := u.quota.ObjectMeta.Name
+	size := coredumpSize(cd)
+	samePod := func(other *coredump.Coredump) bool {
+		return u.inScope(other) && other.Spec.Pod == cd.Spec.Pod
+	}
+	sameContainer := func(other *coredump.Coredump) bool {
+		return samePod(other) && other.Spec.ContainerName == cd.Spec.ContainerName
+	}
+
+	if spec.Hard != nil {
+		totalSize := u.used.DeepCopy()
+		totalSize.Add(size)
+		if totalSize.Cmp(*spec.Hard) > 0 {
+			return &exceededLimit{
+				message:   fmt.Sprintf("Quota exceed, required %s, but %s has only %s", totalSize.String(), name, spec.Hard.String()),
+				reducedBy: u.inScope,
+			}
+		}
+	}
+	if spec.MaxCount != nil && u.count >= *spec.MaxCount {
+		return &exceededLimit{
+			message:   fmt.Sprintf("Quota exceed, %s allows only %d coredumps", name, *spec.MaxCount),
+			reducedBy: u.inScope,
+		}
+	}
+	pod := u.pods[cd.Spec.Pod]
+	if spec.PerPodHard != nil {
+		totalSize := size
+		if pod != nil {
+			totalSize.Add(pod.used)
+		}
+		if totalSize.Cmp(*spec.PerPodHard) > 0 {
+			return &exceededLimit{
+				message:   fmt.Sprintf("Quota exceed, pod %s requires %s, but %s has only %s for each pod", cd.Spec.Pod, totalSize.String(), name, spec.PerPodHard.String()),
+				reducedBy: samePod,
+			}
+		}
+	}
+	if spec.PerContainerMaxCount != nil {
+		var count int64
+		if pod != nil {
+			count = pod.containers[cd.Spec.ContainerName]
+		}
+		if count >= *spec.PerContainerMaxCount {
+			return &exceededLimit{
+				message:   fmt.Sprintf("Quota exceed, %s allows only %d coredumps for each container", name, *spec.PerContainerMaxCount),
+				reducedBy: sameContainer,
+			}
+		}
+	}
+	return nil
+}
+
+// status returns the status of the quota reporting the usage. The usage of
+// each pod is only reported if there is a limit of pods or containers.
+func (u *quotaUsage) status() coredump.QuotaStatus {
+	used := u.used.DeepCopy()
+	status := coredump.QuotaStatus{
+		Hard:  u.quota.Spec.Hard,
+		Used:  &used,
+		Count: u.count,
+	}
+	if u.quota.Spec.PerPodHard == nil && u.quota.Spec.PerContainerMaxCount == nil {
+		return status
+	}
+	status.Pods = map[string]coredump.PodQuotaUsage{}
+	for name, pod := range u.pods {
+		used := pod.used.DeepCopy()
+		containers := map[string]int64{}
+		for container, count := range pod.containers {
+			containers[container] = count
+		}
+		status.Pods[name] = coredump.PodQuotaUsage{
+			Used:       &used,
+			Containers: containers,
+		}
+	}
+	return status
+}
+
+// updateQuotaUsage sets the status of quotas to their usage. Quotas are
+// updated with the resourceVersion they're read with, a conflict is
+// returned as an error. Quotas whose status is up to date are skipped.
+func (c *CoredumpController) updateQuotaUsage(usages []*quotaUsage) error {
+	var errs []error
+	for _, u := range usages {
+		q := u.quota
+		status := u.status()
+		if quotaStatusEqual(&q.Status, &status) {
+			continue
+		}
+		q.Status = status
+		if _, err := c.CoredumpClient.CoredumpV1alpha1().CoredumpQuotas(q.ObjectMeta.Namespace).UpdateStatus(q); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		glog.V(2).Infof("Updated usage of quota %s/%s to %s in %d coredumps", q.ObjectMeta.Namespace, q.ObjectMeta.Name, status.Used.String(), status.Count)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func quotaStatusEqual(a, b *coredump.QuotaStatus) bool {
+	if !quantityEqual(a.Hard, b.Hard) || !quantityEqual(a.Used, b.Used) || a.Count != b.Count || len(a.Pods) != len(b.Pods) {
+		return false
+	}
+	for name, pod := range a.Pods {
+		other, ok := b.Pods[name]
+		if !ok || !quantityEqual(pod.Used, other.Used) || !reflect.DeepEqual(pod.Containers, other.Containers) {
+			return false
+		}
+	}
+	return true
+}
+
+func quantityEqual(a, b *resource.Quantity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(*b) == 0
+}
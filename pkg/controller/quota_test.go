@@ -0,0 +1,240 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+var dumpTime = time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// newCoredump returns a coredump of the container in pod, which happened
+// minute minutes after dumpTime.
+func newCoredump(name, pod, container, size string, minute int, state coredump.CoredumpState) coredump.Coredump {
+	quantity := resource.MustParse(size)
+	return coredump.Coredump{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: coredump.CoredumpSpec{
+			Pod:           pod,
+			ContainerName: container,
+			Size:          &quantity,
+			Time:          metav1.NewTime(dumpTime.Add(time.Duration(minute) * time.Minute)),
+			PodLabels:     map[string]string{"app": pod},
+		},
+		Status: coredump.CoredumpStatus{State: state},
+	}
+}
+
+func newQuota(spec coredump.QuotaSpec) *coredump.CoredumpQuota {
+	return &coredump.CoredumpQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func count(n int64) *int64 {
+	return &n
+}
+
+func TestNewQuotaUsage(t *testing.T) {
+	now := metav1.Now()
+	diskPressure := newCoredump("disk-pressure", "a", "c", "1Mi", 0, coredump.CoredumpStateFailed)
+	diskPressure.Status.Reason = coredump.CoredumpReasonNodeDiskPressure
+	deleting := newCoredump("deleting", "a", "c", "1Mi", 0, coredump.CoredumpStateProcessed)
+	deleting.ObjectMeta.DeletionTimestamp = &now
+	deletingWithFile := newCoredump("deleting-with-file", "a", "c", "2Mi", 0, coredump.CoredumpStateProcessed)
+	deletingWithFile.ObjectMeta.DeletionTimestamp = &now
+	deletingWithFile.ObjectMeta.Finalizers = []string{coredump.FileCleanupFinalizer}
+
+	coredumps := []coredump.Coredump{
+		newCoredump("created", "a", "c", "1Mi", 0, coredump.CoredumpStateCreated),
+		newCoredump("denied", "a", "c", "1Mi", 0, coredump.CoredumpStateDenied),
+		newCoredump("allowed", "a", "c", "4Mi", 0, coredump.CoredumpStateStateAllowed),
+		newCoredump("saved", "b", "c", "8Mi", 0, coredump.CoredumpStateProcessed),
+		newCoredump("failed", "a", "d", "16Mi", 0, coredump.CoredumpStateFailed),
+		diskPressure,
+		deleting,
+		deletingWithFile,
+	}
+	u, err := newQuotaUsage(newQuota(coredump.QuotaSpec{}), coredumps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := resource.MustParse("30Mi"); u.used.Cmp(want) != 0 || u.count != 4 {
+		t.Errorf("usage = %s in %d coredumps, want %s in 4 coredumps", u.used.String(), u.count, want.String())
+	}
+	if pod := u.pods["a"]; pod == nil || pod.containers["c"] != 2 || pod.containers["d"] != 1 {
+		t.Errorf("usage of pod a = %+v, want 2 coredumps of c and 1 of d", pod)
+	}
+
+	selected, err := newQuotaUsage(newQuota(coredump.QuotaSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}},
+	}), coredumps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := resource.MustParse("8Mi"); selected.used.Cmp(want) != 0 || selected.count != 1 {
+		t.Errorf("usage of selected pods = %s in %d coredumps, want %s in 1 coredump", selected.used.String(), selected.count, want.String())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	existing := []coredump.Coredump{
+		newCoredump("a-1", "a", "c1", "3Mi", 0, coredump.CoredumpStateProcessed),
+		newCoredump("a-2", "a", "c2", "1Mi", 1, coredump.CoredumpStateProcessed),
+		newCoredump("b-1", "b", "c1", "2Mi", 2, coredump.CoredumpStateProcessed),
+	}
+	for _, tc := range []struct {
+		name string
+		spec coredump.QuotaSpec
+		cd   coredump.Coredump
+		// want is a substring of the message of the limit exceeded, empty
+		// if cd fits.
+		want string
+	}{
+		{
+			name: "no limit",
+			spec: coredump.QuotaSpec{},
+			cd:   newCoredump("new", "a", "c1", "1Gi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "hard exactly reached",
+			spec: coredump.QuotaSpec{Hard: quantity("10Mi")},
+			cd:   newCoredump("new", "a", "c1", "4Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "hard exceeded by a byte",
+			spec: coredump.QuotaSpec{Hard: quantity("10Mi")},
+			cd:   newCoredump("new", "a", "c1", "4194305", 3, coredump.CoredumpStateCreated),
+			want: "has only 10Mi",
+		},
+		{
+			name: "count below max",
+			spec: coredump.QuotaSpec{MaxCount: count(4)},
+			cd:   newCoredump("new", "a", "c1", "1Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "count reached max",
+			spec: coredump.QuotaSpec{MaxCount: count(3)},
+			cd:   newCoredump("new", "a", "c1", "1Mi", 3, coredump.CoredumpStateCreated),
+			want: "allows only 3 coredumps",
+		},
+		{
+			name: "per pod exactly reached",
+			spec: coredump.QuotaSpec{PerPodHard: quantity("5Mi")},
+			cd:   newCoredump("new", "a", "c3", "1Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "per pod exceeded",
+			spec: coredump.QuotaSpec{PerPodHard: quantity("5Mi")},
+			cd:   newCoredump("new", "a", "c3", "2Mi", 3, coredump.CoredumpStateCreated),
+			want: "for each pod",
+		},
+		{
+			name: "per pod of another pod",
+			spec: coredump.QuotaSpec{PerPodHard: quantity("5Mi")},
+			cd:   newCoredump("new", "b", "c1", "3Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "per pod of a new pod",
+			spec: coredump.QuotaSpec{PerPodHard: quantity("5Mi")},
+			cd:   newCoredump("new", "c", "c1", "6Mi", 3, coredump.CoredumpStateCreated),
+			want: "for each pod",
+		},
+		{
+			name: "per container reached",
+			spec: coredump.QuotaSpec{PerContainerMaxCount: count(1)},
+			cd:   newCoredump("new", "a", "c1", "1Mi", 3, coredump.CoredumpStateCreated),
+			want: "for each container",
+		},
+		{
+			name: "per container of another container",
+			spec: coredump.QuotaSpec{PerContainerMaxCount: count(1)},
+			cd:   newCoredump("new", "a", "c3", "1Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "per container of the same name in another pod",
+			spec: coredump.QuotaSpec{PerContainerMaxCount: count(2)},
+			cd:   newCoredump("new", "b", "c1", "1Mi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "out of selector",
+			spec: coredump.QuotaSpec{
+				Hard:     quantity("1Mi"),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}},
+			},
+			cd: newCoredump("new", "a", "c1", "1Gi", 3, coredump.CoredumpStateCreated),
+		},
+		{
+			name: "in selector",
+			spec: coredump.QuotaSpec{
+				Hard:     quantity("2Mi"),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}},
+			},
+			cd:   newCoredump("new", "b", "c2", "1Mi", 3, coredump.CoredumpStateCreated),
+			want: "has only 2Mi",
+		},
+	} {
+		u, err := newQuotaUsage(newQuota(tc.spec), existing)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		limit := u.check(&tc.cd)
+		switch {
+		case tc.want == "" && limit != nil:
+			t.Errorf("%s: exceeded %q, want fit", tc.name, limit.message)
+		case tc.want != "" && limit == nil:
+			t.Errorf("%s: fit, want exceeded %q", tc.name, tc.want)
+		case tc.want != "" && !strings.Contains(limit.message, tc.want):
+			t.Errorf("%s: exceeded %q, want %q", tc.name, limit.message, tc.want)
+		}
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	u, err := newQuotaUsage(newQuota(coredump.QuotaSpec{PerPodHard: quantity("1Gi")}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newCoredump("a-1", "a", "c1", "3Mi", 0, coredump.CoredumpStateProcessed)
+	b := newCoredump("b-1", "b", "c1", "2Mi", 1, coredump.CoredumpStateProcessed)
+	u.add(&a)
+	u.add(&b)
+	u.remove(&a)
+	if want := resource.MustParse("2Mi"); u.used.Cmp(want) != 0 || u.count != 1 {
+		t.Errorf("usage = %s in %d coredumps, want %s in 1 coredump", u.used.String(), u.count, want.String())
+	}
+	if _, ok := u.pods["a"]; ok {
+		t.Error("usage of pod a is kept after its coredumps are removed")
+	}
+	status := u.status()
+	if len(status.Pods) != 1 || status.Pods["b"].Containers["c1"] != 1 {
+		t.Errorf("pods of status = %+v, want 1 coredump of b/c1", status.Pods)
+	}
+}
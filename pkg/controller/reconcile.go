@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// runQuotaReconciler queues the namespaces of all quotas periodically until
+// ctx is done. Usage of quotas may drift if a delete event is missed, or an
+// update of the quota fails after coredumps are admitted.
+func (c *CoredumpController) runQuotaReconciler(ctx context.Context) {
+	if c.options.QuotaReconcilePeriod <= 0 {
+		return
+	}
+	wait.Until(c.reconcileQuotas, c.options.QuotaReconcilePeriod, ctx.Done())
+}
+
+// reconcileQuotas queues the namespaces of all quotas, which are checked for
+// drift when they're synced. Syncing the namespace recomputes the usage of
+// quotas from the coredumps and corrects them.
+func (c *CoredumpController) reconcileQuotas() {
+	quotas, err := c.quotaLister.List(labels.Everything())
+	if err != nil {
+		glog.Errorf("Failed to list coredump quotas: %v", err)
+		return
+	}
+	for _, q := range quotas {
+		c.reconcileLock.Lock()
+		c.reconcileRequests.Insert(q.ObjectMeta.Namespace)
+		c.reconcileLock.Unlock()
+		c.queue.Add(q.ObjectMeta.Namespace)
+	}
+}
+
+// takeReconcileRequest returns whether namespace is queued by the quota
+// reconciler, and clears the request.
+func (c *CoredumpController) takeReconcileRequest(namespace string) bool {
+	c.reconcileLock.Lock()
+	defer c.reconcileLock.Unlock()
+	if !c.reconcileRequests.Has(namespace) {
+		return false
+	}
+	c.reconcileRequests.Delete(namespace)
+	return true
+}
+
+// reportDrift records a warning event of the quotas whose used size or
+// count differs from the usage recomputed from coredumps. Quotas never
+// synced have no used, and aren't reported.
+func (c *CoredumpController) reportDrift(usages []*quotaUsage) {
+	for _, u := range usages {
+		q := u.quota
+		if q.Status.Used == nil || (quantityEqual(q.Status.Used, &u.used) && q.Status.Count == u.count) {
+			continue
+		}
+		glog.Warningf("Usage of quota %s/%s drifted, recorded %s in %d coredumps, recomputed %s in %d coredumps",
+			q.ObjectMeta.Namespace, q.ObjectMeta.Name, q.Status.Used.String(), q.Status.Count, u.used.String(), u.count)
+		c.recorder.Eventf(q, v1.EventTypeWarning, "QuotaUsageDrift",
+			"Used %s in %d coredumps doesn't match %s in %d coredumps consuming quota, it's corrected",
+			q.Status.Used.String(), q.Status.Count, u.used.String(), u.count)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+// compressionExts are the file name extensions of compressed coredump files.
+var compressionExts = map[string]string{
+	coredump.CompressionNone: "",
+	coredump.CompressionGzip: ".gz",
+	coredump.CompressionZstd: ".zst",
+}
+
+// ParseCompression converts the value of compression option to the
+// compression recorded in Coredump objects.
+func ParseCompression(value string) (string, error) {
+	if value == "none" {
+		return coredump.CompressionNone, nil
+	}
+	if _, ok := compressionExts[value]; !ok {
+		return "", fmt.Errorf("unsupported compression %q", value)
+	}
+	return value, nil
+}
+
+// newCompressor returns a writer which compresses data written to it and
+// writes the result to w. Close must be called to flush the compressed data.
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case coredump.CompressionNone:
+		return nopCloser{w}, nil
+	case coredump.CompressionGzip:
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	case coredump.CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	}
+	return nil, fmt.Errorf("unsupported compression %q", compression)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// countingWriter counts bytes written to the underlying writer. If the limit
+// is positive, only bytes within the limit are written, and it fails once the
+// count would exceed the limit.
+type countingWriter struct {
+	w        io.Writer
+	n        int64
+	limit    int64
+	exceeded bool
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.limit > 0 && c.n+int64(len(p)) > c.limit {
+		c.exceeded = true
+		n, err := c.w.Write(p[:c.limit-c.n])
+		c.n += int64(n)
+		if err != nil {
+			return n, err
+		}
+		return n, errTooLarge
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
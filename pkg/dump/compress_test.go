@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"bytes"
+	"testing"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+func TestParseCompression(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: coredump.CompressionNone},
+		{value: "none", want: coredump.CompressionNone},
+		{value: "gzip", want: coredump.CompressionGzip},
+		{value: "zstd", want: coredump.CompressionZstd},
+		{value: "xz", wantErr: true},
+	} {
+		got, err := ParseCompression(tc.value)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseCompression(%q) = %q, %v, want %q, error %v", tc.value, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		limit int64
+		// writes are the sizes of the writes.
+		writes       []int
+		wantN        int64
+		wantExceeded bool
+	}{
+		{name: "unlimited", limit: 0, writes: []int{10, 10}, wantN: 20},
+		{name: "under the limit", limit: 10, writes: []int{4, 5}, wantN: 9},
+		{name: "exactly at the limit", limit: 10, writes: []int{4, 6}, wantN: 10},
+		{name: "at the limit then one more byte", limit: 10, writes: []int{10, 1}, wantN: 10, wantExceeded: true},
+		{name: "over the limit", limit: 10, writes: []int{4, 7}, wantN: 10, wantExceeded: true},
+		{name: "first write over the limit", limit: 10, writes: []int{11}, wantN: 10, wantExceeded: true},
+	} {
+		var buf bytes.Buffer
+		w := &countingWriter{w: &buf, limit: tc.limit}
+		var err error
+		for i, size := range tc.writes {
+			var n int
+			n, err = w.Write(bytes.Repeat([]byte{'x'}, size))
+			if err != nil {
+				if i != len(tc.writes)-1 {
+					t.Errorf("%s: write %d failed: %v", tc.name, i, err)
+				}
+				if want := tc.limit - (w.n - int64(n)); int64(n) != want {
+					t.Errorf("%s: write %d wrote %d bytes, want %d up to the limit", tc.name, i, n, want)
+				}
+				break
+			}
+			if n != size {
+				t.Errorf("%s: write %d wrote %d bytes, want %d", tc.name, i, n, size)
+			}
+		}
+		if tc.wantExceeded && err != errTooLarge {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, errTooLarge)
+		}
+		if !tc.wantExceeded && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if w.exceeded != tc.wantExceeded {
+			t.Errorf("%s: exceeded = %v, want %v", tc.name, w.exceeded, tc.wantExceeded)
+		}
+		if w.n != tc.wantN || int64(buf.Len()) != tc.wantN {
+			t.Errorf("%s: counted %d bytes and wrote %d, want %d", tc.name, w.n, buf.Len(), tc.wantN)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"strconv"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/elfcore"
+)
+
+// newCrash returns the crash described by the ELF notes. The command line
+// read from /proc is preferred, the one in the notes is truncated to 80
+// bytes.
+func newCrash(notes *elfcore.Notes, cmdline string) *coredump.CoredumpCrash {
+	if notes == nil {
+		return nil
+	}
+	if cmdline == "" {
+		cmdline = notes.Psargs
+	}
+	crash := &coredump.CoredumpCrash{
+		Signal:       notes.Signal,
+		SignalCode:   notes.SignalCode,
+		TID:          notes.TID,
+		Cmdline:      cmdline,
+		Architecture: notes.Architecture,
+	}
+	if notes.Signal > 0 {
+		crash.SignalName = elfcore.SignalName(notes.Signal)
+	}
+	if notes.FaultAddress != nil {
+		crash.FaultAddress = hex(*notes.FaultAddress)
+	}
+	if len(notes.Registers) > 0 {
+		crash.Registers = make(map[string]string, len(notes.Registers))
+		for _, r := range notes.Registers {
+			crash.Registers[r.Name] = hex(r.Value)
+		}
+	}
+	return crash
+}
+
+func hex(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
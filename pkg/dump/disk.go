@@ -0,0 +1,106 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"fmt"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"k8s.io/coredump-detector/cmd/options"
+)
+
+// ParseMinFreeBytes converts the value of min free bytes option to bytes, 0
+// means not set.
+func ParseMinFreeBytes(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid min free bytes %q: %v", value, err)
+	}
+	if q.Sign() < 0 {
+		return 0, fmt.Errorf("invalid min free bytes %q: must not be negative", value)
+	}
+	return q.Value(), nil
+}
+
+// ValidateMinFreePercent checks the value of min free percent option.
+func ValidateMinFreePercent(value int) error {
+	if value < 0 || value >= 100 {
+		return fmt.Errorf("invalid min free percent %d: must be in [0, 100)", value)
+	}
+	return nil
+}
+
+// statfs is replaced in tests.
+var statfs = syscall.Statfs
+
+// diskSpace is the space of the file system of DumpDir which coredumps can
+// use.
+type diskSpace struct {
+	// Available is the bytes which can be written before the free space of
+	// the file system goes below the reserved space.
+	Available int64
+	// Reserved is the free space kept for other users of the node, e.g.
+	// kubelet and pods.
+	Reserved int64
+}
+
+// AvailableBytes returns the bytes which can be written in dir keeping the
+// min free space, or -1 if no min free space is set.
+func AvailableBytes(dir string, cdo *options.CoredumpDetectorOptions) (int64, error) {
+	space, err := availableSpace(dir, cdo)
+	if err != nil || space == nil {
+		return -1, err
+	}
+	return space.Available, nil
+}
+
+// availableSpace returns the space in dir which can be used by the coredump,
+// or nil if no min free space is set.
+func availableSpace(dir string, cdo *options.CoredumpDetectorOptions) (*diskSpace, error) {
+	minFreeBytes, err := ParseMinFreeBytes(cdo.MinFreeBytes)
+	if err != nil {
+		return nil, err
+	}
+	if minFreeBytes == 0 && cdo.MinFreePercent == 0 {
+		return nil, nil
+	}
+	var st syscall.Statfs_t
+	if err := statfs(dir, &st); err != nil {
+		return nil, fmt.Errorf("failed to statfs %s: %v", dir, err)
+	}
+	reserved := int64(st.Blocks) * int64(st.Bsize) * int64(cdo.MinFreePercent) / 100
+	if minFreeBytes > reserved {
+		reserved = minFreeBytes
+	}
+	available := int64(st.Bavail)*int64(st.Bsize) - reserved
+	if available < 0 {
+		available = 0
+	}
+	return &diskSpace{Available: available, Reserved: reserved}, nil
+}
+
+// message explains why a coredump exceeding the space is rejected.
+func (s *diskSpace) message(dumpDir string) string {
+	return fmt.Sprintf("node disk pressure, only %s is available in %s keeping %s free",
+		resource.NewQuantity(s.Available, resource.BinarySI).String(), dumpDir,
+		resource.NewQuantity(s.Reserved, resource.BinarySI).String())
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"syscall"
+	"testing"
+
+	"k8s.io/coredump-detector/cmd/options"
+)
+
+// fakeStatfs replaces statfs with a file system of 1000 blocks of 1KiB, with
+// available blocks free, until the returned func is called.
+func fakeStatfs(available uint64) func() {
+	statfs = func(path string, st *syscall.Statfs_t) error {
+		st.Bsize = 1024
+		st.Blocks = 1000
+		st.Bavail = available
+		return nil
+	}
+	return func() { statfs = syscall.Statfs }
+}
+
+func TestAvailableSpace(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		minFreeBytes   string
+		minFreePercent int
+		// bavail is the number of available blocks of 1KiB.
+		bavail uint64
+		// wantAvailable is -1 if the space isn't limited.
+		wantAvailable int64
+		wantReserved  int64
+	}{
+		{name: "unlimited", bavail: 100, wantAvailable: -1},
+		{name: "min free bytes", minFreeBytes: "100Ki", bavail: 500, wantAvailable: 400 << 10, wantReserved: 100 << 10},
+		{name: "min free percent", minFreePercent: 10, bavail: 500, wantAvailable: 400 << 10, wantReserved: 100 << 10},
+		{name: "larger of bytes and percent", minFreeBytes: "200Ki", minFreePercent: 10, bavail: 500, wantAvailable: 300 << 10, wantReserved: 200 << 10},
+		{name: "larger of percent and bytes", minFreeBytes: "50Ki", minFreePercent: 10, bavail: 500, wantAvailable: 400 << 10, wantReserved: 100 << 10},
+		{name: "exactly the reserved space free", minFreeBytes: "100Ki", bavail: 100, wantAvailable: 0, wantReserved: 100 << 10},
+		{name: "less than the reserved space free", minFreeBytes: "100Ki", bavail: 10, wantAvailable: 0, wantReserved: 100 << 10},
+	} {
+		restore := fakeStatfs(tc.bavail)
+		cdo := &options.CoredumpDetectorOptions{MinFreeBytes: tc.minFreeBytes, MinFreePercent: tc.minFreePercent}
+		space, err := availableSpace("/dump", cdo)
+		restore()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if tc.wantAvailable < 0 {
+			if space != nil {
+				t.Errorf("%s: space = %+v, want unlimited", tc.name, space)
+			}
+			continue
+		}
+		if space == nil || space.Available != tc.wantAvailable || space.Reserved != tc.wantReserved {
+			t.Errorf("%s: space = %+v, want available %d reserved %d", tc.name, space, tc.wantAvailable, tc.wantReserved)
+		}
+	}
+}
+
+func TestAvailableSpaceStatfsError(t *testing.T) {
+	statfs = func(path string, st *syscall.Statfs_t) error {
+		return syscall.ENOENT
+	}
+	defer func() { statfs = syscall.Statfs }()
+	cdo := &options.CoredumpDetectorOptions{MinFreeBytes: "1Mi"}
+	if available, err := AvailableBytes("/dump", cdo); err == nil || available != -1 {
+		t.Errorf("AvailableBytes = %d, %v, want the error of statfs", available, err)
+	}
+}
+
+func TestValidateMinFree(t *testing.T) {
+	for _, value := range []string{"", "0", "1Gi"} {
+		if _, err := ParseMinFreeBytes(value); err != nil {
+			t.Errorf("ParseMinFreeBytes(%q) failed: %v", value, err)
+		}
+	}
+	for _, value := range []string{"-1", "bad"} {
+		if _, err := ParseMinFreeBytes(value); err == nil {
+			t.Errorf("ParseMinFreeBytes(%q) succeeded", value)
+		}
+	}
+	for _, value := range []int{0, 99} {
+		if err := ValidateMinFreePercent(value); err != nil {
+			t.Errorf("ValidateMinFreePercent(%d) failed: %v", value, err)
+		}
+	}
+	for _, value := range []int{-1, 100} {
+		if err := ValidateMinFreePercent(value); err == nil {
+			t.Errorf("ValidateMinFreePercent(%d) succeeded", value)
+		}
+	}
+}
@@ -17,22 +17,25 @@ limitations under the License.
 package dump
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"strconv"
-	"strings"
 	"time"
 
 	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
 	"k8s.io/coredump-detector/cmd/options"
 	"k8s.io/coredump-detector/pkg/apiextensions"
+	"k8s.io/coredump-detector/pkg/elfcore"
 	"k8s.io/coredump-detector/pkg/kube"
-	"k8s.io/coredump-detector/pkg/libdocker"
+	"k8s.io/coredump-detector/pkg/policy"
+	"k8s.io/coredump-detector/pkg/resolver"
+	"k8s.io/coredump-detector/pkg/sparse"
 
-	"github.com/docker/docker/api/types"
 	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,152 +50,439 @@ type DumpInfo struct {
 	Pid           string
 	Filename      string
 	Time          string
+	NodeName      string
+	PodLabels     map[string]string
+	// Cmdline is the command line of the process, empty if it's not read.
+	Cmdline string
+	// ProcPid is the pid whose /proc is read while the coredump is
+	// written, empty if the process already exited.
+	ProcPid string
+	// Suppressed is the number of coredumps of the container dropped by the
+	// rate limit before this one.
+	Suppressed int64
 }
 
-func Dump(kc kube.Client, dc libdocker.Client, progressInfo *options.ProgressInfo, options *options.CoredumpDetectorOptions) error {
+// Request is a coredump to handle. It's also sent by coredump-detector to
+// coredump-agent, and kept with spooled coredumps.
+type Request struct {
+	options.ProgressInfo
+	// ContainerID is the id of the container of the process, read from its
+	// cgroup when it crashed. It's empty if the process is not in a pod or
+	// the cgroup can't be parsed.
+	ContainerID string `json:",omitempty"`
+	// Cmdline is the command line of the process, read when it crashed.
+	Cmdline string `json:",omitempty"`
+	// Spooled is true if the coredump is spooled, the process already
+	// exited when it's handled.
+	Spooled bool `json:",omitempty"`
+	// Attempts is the number of times the spooled coredump failed to be
+	// handled.
+	Attempts int `json:",omitempty"`
+	// Suppressed is the number of coredumps of the container dropped by the
+	// rate limit before this one, since FirstSuppressed.
+	Suppressed      int64      `json:",omitempty"`
+	FirstSuppressed *time.Time `json:",omitempty"`
+}
+
+// coredumpFile describes a coredump file written in host.
+type coredumpFile struct {
+	// RawSize is the size of the coredump before compression.
+	RawSize int64
+	// Size is the apparent size of the file.
+	Size int64
+	// AllocatedSize is the disk space allocated for the file, it's less than
+	// Size if zero blocks of the coredump are stored as holes.
+	AllocatedSize int64
+	// Truncated is true if the coredump is truncated at the size limit.
+	Truncated bool
+	// Notes are the ELF notes of the coredump, nil if they can't be parsed.
+	Notes *elfcore.Notes
+	// Modules are the files mapped in the process, read from the notes.
+	Modules []coredump.CoredumpModule
+}
+
+// OthersDir is the directory relative to DumpDir where coredump files of
+// processes not in pods are saved.
+const OthersDir = "others"
+
+// errTooLarge is returned by save if the coredump exceeds the max size.
+var errTooLarge = errors.New("coredump exceeds the max size")
+
+// ErrPodNotFound is returned by Dump if the pod of the container doesn't
+// exist anymore, or it's another pod with the same name.
+var ErrPodNotFound = errors.New("pod of the container is not found")
+
+// CacheDir returns the directory relative to DumpDir where coredump files of
+// the container are cached. The same layout is used in the persistent volume.
+func CacheDir(namespace, pod, uid, containerName string) string {
+	return path.Join(namespace, pod+"-"+uid, containerName)
+}
+
+// CachePath returns the path of the coredump file in host cache for the
+// Coredump object.
+func CachePath(dumpDir string, cd *coredump.Coredump) string {
+	dirname := CacheDir(cd.ObjectMeta.Namespace, cd.Spec.Pod, string(cd.Spec.Uid), cd.Spec.ContainerName)
+	return path.Join(dumpDir, dirname, cd.ObjectMeta.Name+compressionExts[cd.Spec.Compression])
+}
+
+// Dump handles the coredump read from core. It finds the pod of the process,
+// checks the policies and quotas, saves the coredump in host cache, and
+// creates its Coredump object.
+func Dump(kc kube.Client, cc apiextensions.CoredumpClient, r resolver.Resolver, req *Request, core io.Reader, options *options.CoredumpDetectorOptions) error {
+	progressInfo := &req.ProgressInfo
 	if progressInfo.ContainerPid == progressInfo.HostPid {
-		return saveOthers(progressInfo, options)
+		return saveOthers(progressInfo, core, options)
 	}
-	containers, err := dc.ContainerList(types.ContainerListOptions{})
+	container, err := r.Resolve(progressInfo.HostPid)
 	if err != nil {
 		return err
 	}
-	for _, c := range containers {
-		for _, name := range c.Names {
-			// a k8s container
-			// format of container name:
-			// https://github.com/kubernetes/kubernetes/blob/v1.8.0-beta.1/pkg/kubelet/dockershim/naming.go
-			if strings.HasPrefix(name, "/k8s") {
-				body, err := dc.ContainerTop(c.ID)
-				if err != nil {
-					return err
-				}
-				index := 0
-				// get the index of PID
-				for i, t := range body.Titles {
-					if strings.EqualFold(t, "PID") {
-						index = i
-					}
-				}
-				for _, p := range body.Processes {
-					if p[index] == progressInfo.HostPid {
-						//a progress in k8s pod.
-						// get pod's info from kubernetes cluster
-						dumpInfo, _ := parseContainerName(name, progressInfo)
-						ok, err := validate(dumpInfo, kc)
-						if err != nil {
-							return err
-						}
-						if !ok {
-							glog.Info("can not find pod info from kube-apiserver")
-							return nil
-						}
-						size, err := save(dumpInfo, options)
-						if err != nil {
-							return err
-						}
-						return saveToApiServer(dumpInfo, options, size)
-					}
-				}
-
-			}
+	if container == nil {
+		return saveOthers(progressInfo, core, options)
+	}
+	//a progress in k8s pod.
+	// get pod's info from kubernetes cluster
+	dumpInfo := &DumpInfo{
+		ContainerName: container.ContainerName,
+		Pod:           container.Pod,
+		Namespace:     container.Namespace,
+		Uid:           container.Uid,
+		Pid:           progressInfo.HostPid,
+		Filename:      progressInfo.Filename,
+		Time:          progressInfo.Time,
+		Cmdline:       req.Cmdline,
+		Suppressed:    req.Suppressed,
+	}
+	if !req.Spooled {
+		dumpInfo.ProcPid = progressInfo.HostPid
+	}
+	pod, err := validate(dumpInfo, kc)
+	if err != nil {
+		return err
+	}
+	if pod == nil {
+		return ErrPodNotFound
+	}
+	dumpInfo.NodeName = pod.Spec.NodeName
+	dumpInfo.PodLabels = pod.ObjectMeta.Labels
+	if req.Suppressed > 0 {
+		message := fmt.Sprintf("Coredumps of container %s are handled at a limited rate, %s", dumpInfo.ContainerName, req.suppressedMessage())
+		if err := recordPodEvent(kc, pod, v1.EventTypeWarning, "CoredumpsSuppressed", message); err != nil {
+			glog.Errorf("Failed to record event: %v", err)
 		}
 	}
-	return saveOthers(progressInfo, options)
-}
-
-// saveOthers saves coredump files in host.
-func saveOthers(progressInfo *options.ProgressInfo, options *options.CoredumpDetectorOptions) error {
-	dirname := path.Join(options.DumpDir, "others")
-	if err := os.MkdirAll(dirname, 0775); err != nil {
+	collect, err := collectable(pod, kc)
+	if err != nil {
 		return err
 	}
-	filename := progressInfo.Filename + "-" + progressInfo.HostPid + "-" + progressInfo.Time
-	file, err := os.Create(path.Join(dirname, filename))
+	if !collect {
+		// the core stream is discarded without reading.
+		return discard(kc, pod, dumpInfo, fmt.Sprintf("coredump collection is disabled by %s", coredump.CollectAnnotation))
+	}
+	result, err := evaluatePolicies(dumpInfo, cc)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	if _, err := io.Copy(file, os.Stdin); err != nil {
+	if !result.Allowed {
+		return discard(kc, pod, dumpInfo, result.Message)
+	}
+	limit, deniedMessage, err := admit(dumpInfo, cc, options, result)
+	if err != nil {
 		return err
 	}
-	glog.Infof("Saved dumpfile at: %s\n", file.Name())
-	return nil
+	if deniedMessage != "" {
+		// the core stream is discarded without reading.
+		return saveDenied(dumpInfo, cc, options, deniedMessage)
+	}
+	space, err := availableSpace(options.DumpDir, options)
+	if err != nil {
+		return err
+	}
+	if space != nil {
+		if space.Available == 0 {
+			// the core stream is discarded without reading.
+			return saveDiskPressure(dumpInfo, cc, options, space.message(options.DumpDir))
+		}
+		limit.lowerToDisk(space, options.DumpDir)
+	}
+	truncate := options.OversizeAction == OversizeTruncate
+	file, err := save(dumpInfo, core, options, limit.Size, truncate)
+	if err == errTooLarge {
+		if limit.DiskPressure {
+			return saveDiskPressure(dumpInfo, cc, options, limit.Message)
+		}
+		if limit.ByPolicy {
+			return discard(kc, pod, dumpInfo, limit.Message)
+		}
+		return saveDenied(dumpInfo, cc, options, limit.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if file.Truncated {
+		message := fmt.Sprintf("Truncated coredump of %s in container %s at %d bytes, %s", dumpInfo.Filename, dumpInfo.ContainerName, file.Size, limit.Message)
+		if err := recordPodEvent(kc, pod, v1.EventTypeWarning, "CoredumpTruncated", message); err != nil {
+			glog.Errorf("Failed to record event: %v", err)
+		}
+	}
+	return saveToApiServer(dumpInfo, cc, options, file)
 }
 
-func parseContainerName(name string, progressInfo *options.ProgressInfo) (*DumpInfo, error) {
-	// Docker adds a "/" prefix to names. so trim it.
-	name = strings.TrimPrefix(name, "/")
-
-	parts := strings.Split(name, "_")
-	// Tolerate the random suffix.
-	// TODO: Remove 7 field case when docker 1.11 is deprecated.
-	if len(parts) != 6 && len(parts) != 7 {
-		return nil, fmt.Errorf("failed to parse the container name: %q", name)
+// saveOthers saves coredump files in host. They're limited by the space
+// available in DumpDir only.
+func saveOthers(progressInfo *options.ProgressInfo, core io.Reader, options *options.CoredumpDetectorOptions) error {
+	space, err := availableSpace(options.DumpDir, options)
+	if err != nil {
+		return err
 	}
-	return &DumpInfo{
-		ContainerName: parts[1],
-		Pod:           parts[2],
-		Namespace:     parts[3],
-		Uid:           parts[4],
-		Pid:           progressInfo.HostPid,
-		Filename:      progressInfo.Filename,
-		Time:          progressInfo.Time,
-	}, nil
+	var maxSize int64
+	if space != nil {
+		if space.Available == 0 {
+			glog.Warningf("Discarded coredump of %s, %s", progressInfo.Filename, space.message(options.DumpDir))
+			return nil
+		}
+		maxSize = space.Available
+	}
+	dirname := path.Join(options.DumpDir, OthersDir)
+	if err := os.MkdirAll(dirname, 0775); err != nil {
+		return err
+	}
+	filename := progressInfo.Filename + "-" + progressInfo.HostPid + "-" + progressInfo.Time + compressionExts[options.Compression]
+	truncate := options.OversizeAction == OversizeTruncate
+	if _, err := writeCoredump(path.Join(dirname, filename), core, options.Compression, maxSize, truncate, ""); err != nil {
+		if err == errTooLarge {
+			glog.Warningf("Discarded coredump of %s, %s", progressInfo.Filename, space.message(options.DumpDir))
+			return nil
+		}
+		return err
+	}
+	glog.Infof("Saved dumpfile at: %s\n", path.Join(dirname, filename))
+	return nil
 }
 
-func save(dumpInfo *DumpInfo, options *options.CoredumpDetectorOptions) (int64, error) {
-	dirname := path.Join(options.DumpDir, dumpInfo.Namespace, dumpInfo.Pod+"-"+dumpInfo.Uid, dumpInfo.ContainerName)
+// save saves the coredump in host cache. If maxSize is positive and the
+// stored size exceeds it, the file is truncated at maxSize if truncate is
+// true, otherwise it's removed and errTooLarge is returned.
+func save(dumpInfo *DumpInfo, core io.Reader, options *options.CoredumpDetectorOptions, maxSize int64, truncate bool) (*coredumpFile, error) {
+	dirname := path.Join(options.DumpDir, CacheDir(dumpInfo.Namespace, dumpInfo.Pod, dumpInfo.Uid, dumpInfo.ContainerName))
 	if err := os.MkdirAll(dirname, 0775); err != nil {
-		return 0, err
+		return nil, err
+	}
+	filename := "coredump-" + dumpInfo.Filename + "-" + dumpInfo.Pod + "-" + dumpInfo.Time + compressionExts[options.Compression]
+	file, err := writeCoredump(path.Join(dirname, filename), core, options.Compression, maxSize, truncate, dumpInfo.ProcPid)
+	if err != nil {
+		return nil, err
 	}
-	filename := "coredump-" + dumpInfo.Filename + "-" + dumpInfo.Pod + "-" + dumpInfo.Time
-	file, err := os.Create(path.Join(dirname, filename))
+	if len(file.Modules) > 0 {
+		if err := writeModules(path.Join(dirname, filename), file.Modules); err != nil {
+			glog.Errorf("Failed to write modules of %s: %v", path.Join(dirname, filename), err)
+		}
+	}
+	glog.Infof("Saved dumpfile at: %s\n", path.Join(dirname, filename))
+	return file, nil
+}
+
+// writeCoredump streams the coredump from core into the file through the
+// compressor, and parses its ELF notes on the way. Build-ids of the files
+// mapped are read from /proc of pid, if it's set. Zero blocks are seeked
+// over, so that unused memory in the coredump doesn't take disk space. If
+// maxSize is positive and the stored size exceeds it, reading stops and the
+// file is kept as a truncated coredump if truncate is true, otherwise it's
+// removed and errTooLarge is returned.
+func writeCoredump(filename string, core io.Reader, compression string, maxSize int64, truncate bool, pid string) (*coredumpFile, error) {
+	file, err := os.Create(filename)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer file.Close()
-	size, err := io.Copy(file, os.Stdin)
+	sparseWriter := sparse.NewWriter(file)
+	stored := &countingWriter{w: sparseWriter, limit: maxSize}
+	compressor, err := newCompressor(stored, compression)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	var modules []coredump.CoredumpModule
+	parser := elfcore.NewParser()
+	parser.OnNotes = func(notes *elfcore.Notes) {
+		modules = readModules(notes, pid)
+	}
+	rawSize, err := io.Copy(compressor, io.TeeReader(core, parser))
+	if closeErr := compressor.Close(); err == nil {
+		err = closeErr
+	}
+	if stored.exceeded {
+		if !truncate {
+			file.Close()
+			os.Remove(filename)
+			return nil, errTooLarge
+		}
+		// the error is errTooLarge, a compressed stream misses its end.
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// keep the trailing hole.
+	if err := sparseWriter.Close(); err != nil {
+		return nil, err
 	}
-	glog.Infof("Saved dumpfile at: %s\n", file.Name())
-	return size, nil
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	notes, err := parser.Notes()
+	if err != nil {
+		glog.Warningf("Failed to parse ELF notes of %s: %v", filename, err)
+	}
+	return &coredumpFile{
+		RawSize:       rawSize,
+		Size:          stored.n,
+		AllocatedSize: sparse.AllocatedSize(fi),
+		Truncated:     stored.exceeded,
+		Notes:         notes,
+		Modules:       modules,
+	}, nil
 }
 
 // validate validate the pod info with the kube-apiserver.
-func validate(dumpInfo *DumpInfo, kc kube.Client) (bool, error) {
+// It returns the pod if the dump info matches, otherwise nil.
+func validate(dumpInfo *DumpInfo, kc kube.Client) (*v1.Pod, error) {
 	pod, err := kc.GetPod(dumpInfo.Namespace, dumpInfo.Pod)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	// validate UID
 	if string(pod.ObjectMeta.UID) != dumpInfo.Uid {
-		return false, nil
+		return nil, nil
 	}
 	// validate container name
 	for _, c := range pod.Spec.Containers {
 		if c.Name == dumpInfo.ContainerName {
-			return true, nil
+			return pod, nil
 		}
 	}
-	return false, nil
+	return nil, nil
 }
 
-func saveToApiServer(dumpInfo *DumpInfo, cdo *options.CoredumpDetectorOptions, size int64) error {
-	apiextensionsClient := apiextensions.NewClientOrDie(cdo.KubeConfig)
-	_, err := apiextensionsClient.CreateCoredumpDefinition()
-	if err != nil && !apierrors.IsAlreadyExists(err) {
-		return err
+// evaluatePolicies evaluates the coredump policies in the namespace.
+func evaluatePolicies(dumpInfo *DumpInfo, cc apiextensions.CoredumpClient) (*policy.Result, error) {
+	policies, err := cc.ListCoredumpPolicies(dumpInfo.Namespace)
+	if apierrors.IsNotFound(err) {
+		// CoredumpPolicy is not defined in the cluster.
+		return policy.Evaluate(nil, dumpInfo.PodLabels, dumpInfo.ContainerName, dumpInfo.Filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy.Evaluate(policies.Items, dumpInfo.PodLabels, dumpInfo.ContainerName, dumpInfo.Filename)
+}
+
+// discard records an event of the pod for the discarded coredump.
+func discard(kc kube.Client, pod *v1.Pod, dumpInfo *DumpInfo, reason string) error {
+	glog.Infof("Discarded coredump of %s in pod %s/%s: %s", dumpInfo.Filename, dumpInfo.Namespace, dumpInfo.Pod, reason)
+	message := fmt.Sprintf("Discarded coredump of %s in container %s, %s", dumpInfo.Filename, dumpInfo.ContainerName, reason)
+	return recordPodEvent(kc, pod, v1.EventTypeNormal, "CoredumpDiscarded", message)
+}
+
+// collectable checks whether coredump collection is disabled by the annotation
+// or label of the pod or its namespace.
+func collectable(pod *v1.Pod, kc kube.Client) (bool, error) {
+	if collect, ok := collectValue(pod.ObjectMeta); ok {
+		return collect, nil
 	}
+	ns, err := kc.GetNamespace(pod.ObjectMeta.Namespace)
+	if err != nil {
+		return false, err
+	}
+	if collect, ok := collectValue(ns.ObjectMeta); ok {
+		return collect, nil
+	}
+	return true, nil
+}
 
-	coredumpClient := apiextensions.NewCoredumpClientOrDie(cdo.KubeConfig)
+// collectValue returns the value of CollectAnnotation in annotations or labels,
+// annotation takes precedence over label.
+func collectValue(meta metav1.ObjectMeta) (bool, bool) {
+	value, ok := meta.Annotations[coredump.CollectAnnotation]
+	if !ok {
+		value, ok = meta.Labels[coredump.CollectAnnotation]
+	}
+	if !ok {
+		return false, false
+	}
+	collect, err := strconv.ParseBool(value)
+	if err != nil {
+		glog.Warningf("invalid value %q of %s in %s, ignored", value, coredump.CollectAnnotation, meta.Name)
+		return false, false
+	}
+	return collect, true
+}
+
+func saveToApiServer(dumpInfo *DumpInfo, cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, file *coredumpFile) error {
+	cd := newCoredump(dumpInfo)
+	cd.Spec.Size = resource.NewQuantity(file.Size, resource.BinarySI)
+	cd.Spec.AllocatedSize = resource.NewQuantity(file.AllocatedSize, resource.BinarySI)
+	cd.Spec.RawSize = resource.NewQuantity(file.RawSize, resource.BinarySI)
+	cd.Spec.Compression = cdo.Compression
+	cd.Spec.Truncated = file.Truncated
+	cd.Spec.Crash = newCrash(file.Notes, dumpInfo.Cmdline)
+	cd.Spec.Modules = file.Modules
+	// the file is removed by coredump-saver before the object is deleted.
+	cd.ObjectMeta.Finalizers = []string{coredump.FileCleanupFinalizer}
+	cd.Status = coredump.CoredumpStatus{
+		State:   coredump.CoredumpStateCreated,
+		Message: "Created, not saved yet, need to check quota and then save it to persistent volume",
+	}
+	return register(cc, cdo, cd, dumpInfo.Namespace)
+}
+
+// saveDenied records a Denied Coredump for the coredump rejected before it's
+// written to host cache, so that users know why it's not saved.
+func saveDenied(dumpInfo *DumpInfo, cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, message string) error {
+	glog.Infof("Denied coredump of %s in pod %s/%s: %s", dumpInfo.Filename, dumpInfo.Namespace, dumpInfo.Pod, message)
+	cd := newCoredump(dumpInfo)
+	cd.Spec.Size = resource.NewQuantity(0, resource.BinarySI)
+	cd.Status = coredump.CoredumpStatus{
+		State:   coredump.CoredumpStateDenied,
+		Message: message,
+	}
+	return register(cc, cdo, cd, dumpInfo.Namespace)
+}
+
+// saveDiskPressure records a FailedToSave Coredump for the coredump which
+// doesn't fit in the space available in DumpDir. It has no file, and doesn't
+// consume quota.
+func saveDiskPressure(dumpInfo *DumpInfo, cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, message string) error {
+	glog.Warningf("Discarded coredump of %s in pod %s/%s: %s", dumpInfo.Filename, dumpInfo.Namespace, dumpInfo.Pod, message)
+	cd := newCoredump(dumpInfo)
+	cd.Spec.Size = resource.NewQuantity(0, resource.BinarySI)
+	cd.Status = coredump.CoredumpStatus{
+		State:   coredump.CoredumpStateFailed,
+		Reason:  coredump.CoredumpReasonNodeDiskPressure,
+		Message: message,
+	}
+	return register(cc, cdo, cd, dumpInfo.Namespace)
+}
+
+// newCoredump returns a Coredump object of the dump info, the size and status
+// are set by callers.
+func newCoredump(dumpInfo *DumpInfo) *coredump.Coredump {
 	pid, _ := strconv.Atoi(dumpInfo.Pid)
 	dumptime, _ := strconv.ParseInt(dumpInfo.Time, 10, 64)
-	cd := &coredump.Coredump{
+	var annotations map[string]string
+	if dumpInfo.Suppressed > 0 {
+		annotations = map[string]string{
+			coredump.SuppressedAnnotation: strconv.FormatInt(dumpInfo.Suppressed, 10),
+		}
+	}
+	return &coredump.Coredump{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "coredump-" + dumpInfo.Filename + "-" + dumpInfo.Pod + "-" + dumpInfo.Time,
+			Labels: map[string]string{
+				coredump.CoredumpNodeLabel: dumpInfo.NodeName,
+			},
+			Annotations: annotations,
 		},
 		Spec: coredump.CoredumpSpec{
 			ContainerName: dumpInfo.ContainerName,
@@ -202,13 +492,29 @@ func saveToApiServer(dumpInfo *DumpInfo, cdo *options.CoredumpDetectorOptions, s
 			Filename:      dumpInfo.Filename,
 			Time:          metav1.NewTime(time.Unix(dumptime, 0)),
 			Volume:        "",
-			Size:          resource.NewQuantity(size, resource.BinarySI),
-		},
-		Status: coredump.CoredumpStatus{
-			State:   coredump.CoredumpStateCreated,
-			Message: "Created, not saved yet, need to check quota and then save it to persistent volume",
+			PodLabels:     dumpInfo.PodLabels,
 		},
 	}
-	_, err = coredumpClient.CreateCoredump(cd, dumpInfo.Namespace)
-	return err
+}
+
+// createCoredump creates the Coredump object and sets its status. The
+// definition of Coredump is only created if it's missing in the cluster. It
+// returns the object created, which is nil if the creation failed.
+func createCoredump(cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, cd *coredump.Coredump, namespace string) (*coredump.Coredump, error) {
+	status := cd.Status
+	created, err := cc.CreateCoredump(cd, namespace)
+	if apierrors.IsNotFound(err) {
+		_, err = apiextensions.NewClientOrDie(cdo.KubeConfig).CreateCoredumpDefinition()
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		created, err = cc.CreateCoredump(cd, namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// status is a subresource, which is ignored on creation.
+	created.Status = status
+	_, err = cc.UpdateCoredumpStatus(created)
+	return created, err
 }
@@ -0,0 +1,129 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"strings"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	"k8s.io/coredump-detector/pkg/kube"
+)
+
+const eventComponent = "coredump-detector"
+
+// podReference returns the reference to the pod, as the event recorder of
+// client-go sets in involvedObject.
+func podReference(pod *v1.Pod) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		Kind:            "Pod",
+		APIVersion:      "v1",
+		Namespace:       pod.ObjectMeta.Namespace,
+		Name:            pod.ObjectMeta.Name,
+		UID:             pod.ObjectMeta.UID,
+		ResourceVersion: pod.ObjectMeta.ResourceVersion,
+	}
+}
+
+// nodeReference returns the reference to the node. As kubelet does, the
+// name of the node is its UID, so that events don't need to get the node.
+func nodeReference(nodeName string) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		Kind: "Node",
+		Name: nodeName,
+		UID:  apitypes.UID(nodeName),
+	}
+}
+
+// RecordNodeEvent records an event of the node nodeName, for coredumps whose
+// pod isn't known.
+func RecordNodeEvent(kc kube.Client, nodeName, eventType, reason, message string) error {
+	return recordEvent(kc, nodeReference(nodeName), nodeName, eventType, reason, message)
+}
+
+// recordPodEvent records an event of the pod.
+func recordPodEvent(kc kube.Client, pod *v1.Pod, eventType, reason, message string) error {
+	return recordEvent(kc, podReference(pod), pod.Spec.NodeName, eventType, reason, message)
+}
+
+// recordEvent records an event of the object ref in the node host, as the
+// event recorder of client-go makes it. Events of the object with the same
+// reason are aggregated into one event named after the object and the
+// reason, whose count is the number of times it occurred. Detectors of the
+// same object update the event concurrently, so the update is retried on
+// conflict, an occurrence is only lost if all the retries conflict.
+func recordEvent(kc kube.Client, ref *v1.ObjectReference, host, eventType, reason, message string) error {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	name := ref.Name + "." + strings.ToLower(reason)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := updateEvent(kc, ref, namespace, name, host, eventType, reason, message)
+		if apierrors.IsAlreadyExists(err) {
+			// another detector created the event meanwhile, count on it.
+			return apierrors.NewConflict(v1.Resource("events"), name, err)
+		}
+		return err
+	})
+}
+
+// updateEvent counts an occurrence in the event name, or creates it.
+func updateEvent(kc kube.Client, ref *v1.ObjectReference, namespace, name, host, eventType, reason, message string) error {
+	now := metav1.Now()
+	event, err := kc.GetEvent(namespace, name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && event.InvolvedObject.UID == ref.UID {
+		event.Count++
+		event.Message = message
+		event.LastTimestamp = now
+		_, err = kc.UpdateEvent(event)
+		return err
+	}
+
+	newEvent := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Source: v1.EventSource{
+			Component: eventComponent,
+			Host:      host,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventType,
+	}
+	if err == nil {
+		// the event belongs to a previous object with the same name, reset it.
+		newEvent.ObjectMeta.ResourceVersion = event.ObjectMeta.ResourceVersion
+		_, err = kc.UpdateEvent(newEvent)
+		return err
+	}
+	_, err = kc.CreateEvent(newEvent)
+	return err
+}
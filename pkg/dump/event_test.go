@@ -0,0 +1,186 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"strconv"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+)
+
+// fakeKubeClient keeps events in memory, and checks their resource version
+// on update as the apiserver does. beforeWrite, if set, is called before
+// each write, e.g. to race with another detector.
+type fakeKubeClient struct {
+	events      map[string]*v1.Event
+	version     int
+	beforeWrite func()
+}
+
+func newFakeKubeClient() *fakeKubeClient {
+	return &fakeKubeClient{events: map[string]*v1.Event{}}
+}
+
+func (c *fakeKubeClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	return nil, apierrors.NewNotFound(v1.Resource("pods"), name)
+}
+
+func (c *fakeKubeClient) GetNamespace(name string) (*v1.Namespace, error) {
+	return nil, apierrors.NewNotFound(v1.Resource("namespaces"), name)
+}
+
+func (c *fakeKubeClient) GetEvent(namespace, name string) (*v1.Event, error) {
+	event, ok := c.events[namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(v1.Resource("events"), name)
+	}
+	copied := *event
+	return &copied, nil
+}
+
+func (c *fakeKubeClient) CreateEvent(event *v1.Event) (*v1.Event, error) {
+	c.write()
+	key := event.ObjectMeta.Namespace + "/" + event.ObjectMeta.Name
+	if _, ok := c.events[key]; ok {
+		return nil, apierrors.NewAlreadyExists(v1.Resource("events"), event.ObjectMeta.Name)
+	}
+	return c.store(key, event), nil
+}
+
+func (c *fakeKubeClient) UpdateEvent(event *v1.Event) (*v1.Event, error) {
+	c.write()
+	key := event.ObjectMeta.Namespace + "/" + event.ObjectMeta.Name
+	old, ok := c.events[key]
+	if !ok {
+		return nil, apierrors.NewNotFound(v1.Resource("events"), event.ObjectMeta.Name)
+	}
+	if old.ObjectMeta.ResourceVersion != event.ObjectMeta.ResourceVersion {
+		return nil, apierrors.NewConflict(v1.Resource("events"), event.ObjectMeta.Name, nil)
+	}
+	return c.store(key, event), nil
+}
+
+func (c *fakeKubeClient) write() {
+	if hook := c.beforeWrite; hook != nil {
+		// the hook writes without racing with itself.
+		c.beforeWrite = nil
+		hook()
+	}
+}
+
+func (c *fakeKubeClient) store(key string, event *v1.Event) *v1.Event {
+	c.version++
+	stored := *event
+	stored.ObjectMeta.ResourceVersion = strconv.Itoa(c.version)
+	c.events[key] = &stored
+	return &stored
+}
+
+func newPod(uid string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns", UID: apitypes.UID(uid), ResourceVersion: "7"},
+		Spec:       v1.PodSpec{NodeName: "node1"},
+	}
+}
+
+func TestRecordPodEvent(t *testing.T) {
+	kc := newFakeKubeClient()
+	pod := newPod("uid-1")
+	for i := 0; i < 2; i++ {
+		if err := recordPodEvent(kc, pod, v1.EventTypeWarning, "CoredumpTruncated", "message "+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	event, ok := kc.events["ns/web.coredumptruncated"]
+	if !ok {
+		t.Fatalf("event web.coredumptruncated is not recorded, events: %v", kc.events)
+	}
+	if event.Count != 2 || event.Message != "message 1" {
+		t.Errorf("event count = %d, message = %q, want 2, %q", event.Count, event.Message, "message 1")
+	}
+	want := v1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: "ns", Name: "web", UID: "uid-1", ResourceVersion: "7"}
+	if event.InvolvedObject != want {
+		t.Errorf("involved object = %+v, want %+v", event.InvolvedObject, want)
+	}
+	if event.Source.Component != eventComponent || event.Source.Host != "node1" {
+		t.Errorf("source = %+v, want %s in node1", event.Source, eventComponent)
+	}
+
+	// a new pod with the same name starts a new count.
+	if err := recordPodEvent(kc, newPod("uid-2"), v1.EventTypeWarning, "CoredumpTruncated", "new pod"); err != nil {
+		t.Fatal(err)
+	}
+	if event := kc.events["ns/web.coredumptruncated"]; event.Count != 1 || event.InvolvedObject.UID != "uid-2" {
+		t.Errorf("event of the new pod: count = %d, uid = %s, want 1, uid-2", event.Count, event.InvolvedObject.UID)
+	}
+}
+
+func TestRecordEventRace(t *testing.T) {
+	pod := newPod("uid-1")
+	for _, tc := range []struct {
+		name    string
+		existed bool
+	}{
+		// the other detector creates the event after this one got NotFound.
+		{"concurrent create", false},
+		// the other detector updates the event after this one got it.
+		{"concurrent update", true},
+	} {
+		kc := newFakeKubeClient()
+		if tc.existed {
+			if err := recordPodEvent(kc, pod, v1.EventTypeNormal, "CoredumpDiscarded", "first"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		kc.beforeWrite = func() {
+			if err := recordPodEvent(kc, pod, v1.EventTypeNormal, "CoredumpDiscarded", "other"); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		if err := recordPodEvent(kc, pod, v1.EventTypeNormal, "CoredumpDiscarded", "this"); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		want := int32(2)
+		if tc.existed {
+			want = 3
+		}
+		if event := kc.events["ns/web.coredumpdiscarded"]; event.Count != want {
+			t.Errorf("%s: event count = %d, want %d", tc.name, event.Count, want)
+		}
+	}
+}
+
+func TestRecordNodeEvent(t *testing.T) {
+	kc := newFakeKubeClient()
+	if err := RecordNodeEvent(kc, "node1", v1.EventTypeWarning, "CoredumpDropped", "message"); err != nil {
+		t.Fatal(err)
+	}
+	// events of cluster-scoped objects are in the default namespace.
+	event, ok := kc.events["default/node1.coredumpdropped"]
+	if !ok {
+		t.Fatalf("event node1.coredumpdropped is not recorded, events: %v", kc.events)
+	}
+	want := v1.ObjectReference{Kind: "Node", Name: "node1", UID: "node1"}
+	if event.InvolvedObject != want || event.Source.Host != "node1" {
+		t.Errorf("involved object = %+v in %s, want %+v in node1", event.InvolvedObject, event.Source.Host, want)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/apiextensions"
+	"k8s.io/coredump-detector/pkg/policy"
+)
+
+const (
+	// OversizeDiscard discards coredumps exceeding the size limit.
+	OversizeDiscard = "discard"
+	// OversizeTruncate truncates coredumps at the size limit.
+	OversizeTruncate = "truncate"
+)
+
+// ParseMaxDumpSize converts the value of max dump size option to bytes, 0
+// means unlimited.
+func ParseMaxDumpSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max dump size %q: %v", value, err)
+	}
+	if q.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid max dump size %q: must be positive", value)
+	}
+	return q.Value(), nil
+}
+
+// CompleteOptions checks the options of handling coredumps, and converts the
+// compression to its canonical name.
+func CompleteOptions(cdo *options.CoredumpDetectorOptions) error {
+	compression, err := ParseCompression(cdo.Compression)
+	if err != nil {
+		return err
+	}
+	cdo.Compression = compression
+	if _, err := ParseMaxDumpSize(cdo.MaxDumpSize); err != nil {
+		return err
+	}
+	if err := ValidateOversizeAction(cdo.OversizeAction); err != nil {
+		return err
+	}
+	if _, err := ParseMinFreeBytes(cdo.MinFreeBytes); err != nil {
+		return err
+	}
+	if err := ValidateMinFreePercent(cdo.MinFreePercent); err != nil {
+		return err
+	}
+	return ValidateRateLimit(cdo)
+}
+
+// ValidateOversizeAction checks the value of oversize action option.
+func ValidateOversizeAction(value string) error {
+	if value != OversizeDiscard && value != OversizeTruncate {
+		return fmt.Errorf("unsupported oversize action %q", value)
+	}
+	return nil
+}
+
+// sizeLimit is the max stored size of a coredump. It's checked while the
+// coredump is written to host cache, so that the node disk isn't consumed by
+// coredumps which would be denied by the controller afterwards.
+type sizeLimit struct {
+	// Size is the max size in bytes, 0 means unlimited.
+	Size int64
+	// Message explains why the coredump exceeding the limit is rejected.
+	Message string
+	// ByPolicy is true if the limit is the max size of coredump policies.
+	ByPolicy bool
+	// DiskPressure is true if the limit is the space available in DumpDir.
+	DiskPressure bool
+}
+
+// lower lowers the limit to size if it's less than the current limit.
+func (l *sizeLimit) lower(size int64, message string, byPolicy bool) {
+	if l.Size == 0 || size < l.Size {
+		l.Size = size
+		l.Message = message
+		l.ByPolicy = byPolicy
+		l.DiskPressure = false
+	}
+}
+
+// lowerToDisk lowers the limit to the space available in DumpDir.
+func (l *sizeLimit) lowerToDisk(space *diskSpace, dumpDir string) {
+	if l.Size == 0 || space.Available < l.Size {
+		l.Size = space.Available
+		l.Message = space.message(dumpDir)
+		l.ByPolicy = false
+		l.DiskPressure = true
+	}
+}
+
+// admit checks the size limits and the coredump quotas of the namespace before
+// the coredump is written. It returns the size limit of the coredump, and a
+// non-empty message if the namespace is already over quota.
+func admit(dumpInfo *DumpInfo, cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, result *policy.Result) (*sizeLimit, string, error) {
+	limit := &sizeLimit{}
+	if result.MaxSize != nil {
+		limit.lower(result.MaxSize.Value(), result.TooLargeMessage(), true)
+	}
+	maxDumpSize, err := ParseMaxDumpSize(cdo.MaxDumpSize)
+	if err != nil {
+		return nil, "", err
+	}
+	if maxDumpSize > 0 {
+		limit.lower(maxDumpSize, fmt.Sprintf("coredump exceeds the max dump size %s", cdo.MaxDumpSize), false)
+	}
+
+	quotas, err := cc.ListCoredumpQuotas(dumpInfo.Namespace)
+	if apierrors.IsNotFound(err) {
+		// CoredumpQuota is not defined in the cluster.
+		return limit, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range quotas.Items {
+		q := &quotas.Items[i]
+		if q.Spec.Selector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(q.Spec.Selector)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid selector of quota %s: %v", q.ObjectMeta.Name, err)
+			}
+			if !selector.Matches(labels.Set(dumpInfo.PodLabels)) {
+				continue
+			}
+		}
+		if q.Spec.ExceedPolicy == coredump.QuotaExceedEvictOldest {
+			// the controller evicts saved coredumps to make room, so the
+			// coredump is only limited to the whole quota.
+			if q.Spec.Hard != nil {
+				limit.lower(q.Spec.Hard.Value(), fmt.Sprintf("Quota exceed, %s has only %s", q.ObjectMeta.Name, q.Spec.Hard.String()), false)
+			}
+			if q.Spec.PerPodHard != nil {
+				limit.lower(q.Spec.PerPodHard.Value(), fmt.Sprintf("Quota exceed, %s has only %s for each pod", q.ObjectMeta.Name, q.Spec.PerPodHard.String()), false)
+			}
+			continue
+		}
+		if message := admitCount(dumpInfo, q); message != "" {
+			return nil, message, nil
+		}
+		if q.Spec.Hard != nil {
+			remaining := q.Spec.Hard.DeepCopy()
+			if q.Status.Used != nil {
+				remaining.Sub(*q.Status.Used)
+			}
+			if remaining.Sign() <= 0 {
+				return nil, fmt.Sprintf("Quota exceed, %s has no space left of %s", q.ObjectMeta.Name, q.Spec.Hard.String()), nil
+			}
+			limit.lower(remaining.Value(), fmt.Sprintf("Quota exceed, %s has only %s left", q.ObjectMeta.Name, remaining.String()), false)
+		}
+		if q.Spec.PerPodHard != nil {
+			remaining := q.Spec.PerPodHard.DeepCopy()
+			if used := q.Status.Pods[dumpInfo.Pod].Used; used != nil {
+				remaining.Sub(*used)
+			}
+			if remaining.Sign() <= 0 {
+				return nil, fmt.Sprintf("Quota exceed, pod %s has no space left of %s in %s", dumpInfo.Pod, q.Spec.PerPodHard.String(), q.ObjectMeta.Name), nil
+			}
+			limit.lower(remaining.Value(), fmt.Sprintf("Quota exceed, pod %s has only %s left in %s", dumpInfo.Pod, remaining.String(), q.ObjectMeta.Name), false)
+		}
+	}
+	return limit, "", nil
+}
+
+// admitCount returns a non-empty message if the count limits of the quota
+// are already reached.
+func admitCount(dumpInfo *DumpInfo, q *coredump.CoredumpQuota) string {
+	if q.Spec.MaxCount != nil && q.Status.Count >= *q.Spec.MaxCount {
+		return fmt.Sprintf("Quota exceed, %s allows only %d coredumps", q.ObjectMeta.Name, *q.Spec.MaxCount)
+	}
+	if q.Spec.PerContainerMaxCount != nil && q.Status.Pods[dumpInfo.Pod].Containers[dumpInfo.ContainerName] >= *q.Spec.PerContainerMaxCount {
+		return fmt.Sprintf("Quota exceed, %s allows only %d coredumps for each container", q.ObjectMeta.Name, *q.Spec.PerContainerMaxCount)
+	}
+	return ""
+}
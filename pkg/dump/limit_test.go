@@ -0,0 +1,289 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/policy"
+)
+
+// fakeCoredumpClient keeps coredumps in memory and lists quotas. err, if
+// set, is returned by every call.
+type fakeCoredumpClient struct {
+	coredumps map[string]*coredump.Coredump
+	quotas    []coredump.CoredumpQuota
+	err       error
+}
+
+func newFakeCoredumpClient(quotas ...coredump.CoredumpQuota) *fakeCoredumpClient {
+	return &fakeCoredumpClient{coredumps: map[string]*coredump.Coredump{}, quotas: quotas}
+}
+
+func (c *fakeCoredumpClient) CreateCoredump(cd *coredump.Coredump, namespace string) (*coredump.Coredump, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	key := namespace + "/" + cd.ObjectMeta.Name
+	if _, ok := c.coredumps[key]; ok {
+		return nil, apierrors.NewAlreadyExists(coredump.Resource("coredumps"), cd.ObjectMeta.Name)
+	}
+	created := cd.DeepCopy()
+	created.ObjectMeta.Namespace = namespace
+	created.Status = coredump.CoredumpStatus{}
+	c.coredumps[key] = created
+	return created.DeepCopy(), nil
+}
+
+func (c *fakeCoredumpClient) GetCoredump(namespace, name string) (*coredump.Coredump, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	cd, ok := c.coredumps[namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(coredump.Resource("coredumps"), name)
+	}
+	return cd.DeepCopy(), nil
+}
+
+func (c *fakeCoredumpClient) UpdateCoredump(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.coredumps[cd.ObjectMeta.Namespace+"/"+cd.ObjectMeta.Name] = cd.DeepCopy()
+	return cd, nil
+}
+
+func (c *fakeCoredumpClient) UpdateCoredumpStatus(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	stored, ok := c.coredumps[cd.ObjectMeta.Namespace+"/"+cd.ObjectMeta.Name]
+	if !ok {
+		return nil, apierrors.NewNotFound(coredump.Resource("coredumps"), cd.ObjectMeta.Name)
+	}
+	stored.Status = cd.Status
+	return stored.DeepCopy(), nil
+}
+
+func (c *fakeCoredumpClient) ListCoredumpPolicies(namespace string) (*coredump.CoredumpPolicyList, error) {
+	return &coredump.CoredumpPolicyList{}, c.err
+}
+
+func (c *fakeCoredumpClient) ListCoredumpQuotas(namespace string) (*coredump.CoredumpQuotaList, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &coredump.CoredumpQuotaList{Items: c.quotas}, nil
+}
+
+func (c *fakeCoredumpClient) ListWatchCoredumps(selector labels.Selector) cache.ListerWatcher {
+	return nil
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func count(n int64) *int64 {
+	return &n
+}
+
+func newQuota(name string, spec coredump.QuotaSpec, status coredump.QuotaStatus) coredump.CoredumpQuota {
+	return coredump.CoredumpQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       spec,
+		Status:     status,
+	}
+}
+
+func TestSizeLimitLower(t *testing.T) {
+	l := &sizeLimit{}
+	l.lower(100, "policy", true)
+	if l.Size != 100 || !l.ByPolicy {
+		t.Errorf("unlimited lowered to 100 = %+v", l)
+	}
+	l.lower(100, "equal", false)
+	if l.Message != "policy" {
+		t.Errorf("equal limit replaced the message %q with %q", "policy", l.Message)
+	}
+	l.lowerToDisk(&diskSpace{Available: 50}, "/dump")
+	if l.Size != 50 || l.ByPolicy || !l.DiskPressure {
+		t.Errorf("lowered to disk space 50 = %+v", l)
+	}
+	l.lower(200, "larger", false)
+	if l.Size != 50 {
+		t.Errorf("larger limit raised the size to %d", l.Size)
+	}
+	l.lower(10, "quota", false)
+	if l.Size != 10 || l.DiskPressure || l.Message != "quota" {
+		t.Errorf("lowered to 10 = %+v", l)
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	dumpInfo := &DumpInfo{Namespace: "default", Pod: "web", ContainerName: "app", PodLabels: map[string]string{"app": "web"}}
+	used := coredump.QuotaStatus{
+		Used:  quantity("10Mi"),
+		Count: 2,
+		Pods: map[string]coredump.PodQuotaUsage{
+			"web": {Used: quantity("4Mi"), Containers: map[string]int64{"app": 2}},
+		},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		maxDumpSize string
+		maxSize     string
+		quotas      []coredump.CoredumpQuota
+		// wantSize is the size limit, 0 for unlimited. wantDenied is a
+		// substring of the message of the denied coredump.
+		wantSize     string
+		wantByPolicy bool
+		wantDenied   string
+	}{
+		{
+			name: "no limit",
+		},
+		{
+			name:         "policy max size",
+			maxSize:      "100Mi",
+			maxDumpSize:  "1Gi",
+			wantSize:     "100Mi",
+			wantByPolicy: true,
+		},
+		{
+			name:        "max dump size",
+			maxSize:     "1Gi",
+			maxDumpSize: "100Mi",
+			wantSize:    "100Mi",
+		},
+		{
+			name:     "remaining of hard",
+			quotas:   []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{Hard: quantity("16Mi")}, used)},
+			wantSize: "6Mi",
+		},
+		{
+			name:       "hard exactly used",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{Hard: quantity("10Mi")}, used)},
+			wantDenied: "no space left",
+		},
+		{
+			name:       "hard of 0",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{Hard: quantity("0")}, coredump.QuotaStatus{})},
+			wantDenied: "no space left",
+		},
+		{
+			name:     "remaining of per pod hard",
+			quotas:   []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{Hard: quantity("1Gi"), PerPodHard: quantity("5Mi")}, used)},
+			wantSize: "1Mi",
+		},
+		{
+			name:       "per pod hard exactly used",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{PerPodHard: quantity("4Mi")}, used)},
+			wantDenied: "pod web has no space left",
+		},
+		{
+			name:   "count under the max",
+			quotas: []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{MaxCount: count(3), PerContainerMaxCount: count(3)}, used)},
+		},
+		{
+			name:       "count exactly at the max",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{MaxCount: count(2)}, used)},
+			wantDenied: "allows only 2 coredumps",
+		},
+		{
+			name:       "max count of 0",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{MaxCount: count(0)}, coredump.QuotaStatus{})},
+			wantDenied: "allows only 0 coredumps",
+		},
+		{
+			name:       "container count exactly at the max",
+			quotas:     []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{PerContainerMaxCount: count(2)}, used)},
+			wantDenied: "for each container",
+		},
+		{
+			name:   "quota of other pods",
+			quotas: []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{MaxCount: count(0), Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}, coredump.QuotaStatus{})},
+		},
+		{
+			// the controller evicts saved coredumps, only the whole quota
+			// limits the size.
+			name: "evict oldest",
+			quotas: []coredump.CoredumpQuota{newQuota("q", coredump.QuotaSpec{
+				Hard: quantity("10Mi"), PerPodHard: quantity("8Mi"), MaxCount: count(2), ExceedPolicy: coredump.QuotaExceedEvictOldest,
+			}, used)},
+			wantSize: "8Mi",
+		},
+		{
+			name: "smallest of quotas",
+			quotas: []coredump.CoredumpQuota{
+				newQuota("large", coredump.QuotaSpec{Hard: quantity("1Gi")}, used),
+				newQuota("small", coredump.QuotaSpec{Hard: quantity("12Mi")}, used),
+			},
+			maxSize:  "100Mi",
+			wantSize: "2Mi",
+		},
+	} {
+		cdo := &options.CoredumpDetectorOptions{MaxDumpSize: tc.maxDumpSize}
+		result := &policy.Result{Allowed: true}
+		if tc.maxSize != "" {
+			result.MaxSize = quantity(tc.maxSize)
+		}
+		limit, denied, err := admit(dumpInfo, newFakeCoredumpClient(tc.quotas...), cdo, result)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if tc.wantDenied != "" {
+			if !strings.Contains(denied, tc.wantDenied) {
+				t.Errorf("%s: denied %q, want %q", tc.name, denied, tc.wantDenied)
+			}
+			continue
+		}
+		if denied != "" {
+			t.Errorf("%s: denied %q", tc.name, denied)
+			continue
+		}
+		var wantSize int64
+		if tc.wantSize != "" {
+			wantSize = quantity(tc.wantSize).Value()
+		}
+		if limit.Size != wantSize || limit.ByPolicy != tc.wantByPolicy {
+			t.Errorf("%s: limit = %d by policy %v, want %d by policy %v", tc.name, limit.Size, limit.ByPolicy, wantSize, tc.wantByPolicy)
+		}
+	}
+}
+
+func TestAdmitWithoutQuotaDefinition(t *testing.T) {
+	client := newFakeCoredumpClient()
+	client.err = apierrors.NewNotFound(coredump.Resource("coredumpquotas"), "")
+	limit, denied, err := admit(&DumpInfo{Namespace: "default"}, client, &options.CoredumpDetectorOptions{MaxDumpSize: "1Mi"}, &policy.Result{Allowed: true})
+	if err != nil || denied != "" || limit.Size != 1<<20 {
+		t.Errorf("admit = %+v, %q, %v, want the max dump size", limit, denied, err)
+	}
+}
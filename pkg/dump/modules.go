@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/elfcore"
+)
+
+// ModulesExt is the extension of the sidecar file of a coredump, which lists
+// the modules of the process in JSON, e.g. "<file>.modules.json". It's kept
+// next to the coredump file in host cache and the persistent volume.
+const ModulesExt = ".modules.json"
+
+// atSysinfoEhdr is the auxv entry of the address of the vDSO.
+const atSysinfoEhdr = 33
+
+// deletedSuffix is appended by the kernel to paths of files deleted.
+const deletedSuffix = " (deleted)"
+
+// readModules returns the files mapped in the process listed in NT_FILE, and
+// the vDSO. If pid is set, build-ids are read from the files mapped in the
+// process, so it must be called before the process exits, i.e. while its
+// coredump is being read.
+func readModules(notes *elfcore.Notes, pid string) []coredump.CoredumpModule {
+	var modules []coredump.CoredumpModule
+	index := map[string]int{}
+	for _, f := range notes.Files {
+		i, ok := index[f.Path]
+		if !ok {
+			index[f.Path] = len(modules)
+			modules = append(modules, coredump.CoredumpModule{
+				Path:        f.Path,
+				LoadAddress: hex(f.Start),
+			})
+			if pid != "" {
+				modules[len(modules)-1].BuildID = readBuildID(pid, f)
+			}
+			continue
+		}
+		// the file is loaded at the mapping of its beginning.
+		if f.Offset == 0 && modules[i].LoadAddress != hex(f.Start) {
+			modules[i].LoadAddress = hex(f.Start)
+		}
+	}
+	if vdso, ok := notes.Auxv[atSysinfoEhdr]; ok && vdso != 0 {
+		modules = append(modules, coredump.CoredumpModule{Path: "[vdso]", LoadAddress: hex(vdso)})
+	}
+	return modules
+}
+
+// readBuildID reads the build-id of the mapped file. The mapping itself is
+// read through /proc/<pid>/map_files, so the file is found even if it's
+// deleted or replaced. Otherwise the path is read in the root of the
+// process.
+func readBuildID(pid string, f elfcore.File) string {
+	mapFile := fmt.Sprintf("/proc/%s/map_files/%x-%x", pid, f.Start, f.End)
+	if link, err := os.Readlink(mapFile); err == nil && (link == f.Path || link+deletedSuffix == f.Path) {
+		if id, err := buildID(mapFile); err == nil {
+			return id
+		}
+	}
+	if strings.HasSuffix(f.Path, deletedSuffix) {
+		return ""
+	}
+	id, err := buildID(path.Join("/proc", pid, "root", f.Path))
+	if err != nil {
+		glog.V(4).Infof("Failed to read build-id of %s: %v", f.Path, err)
+		return ""
+	}
+	return id
+}
+
+func buildID(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return elfcore.BuildID(file)
+}
+
+// writeModules writes the sidecar file of the coredump file.
+func writeModules(filename string, modules []coredump.CoredumpModule) error {
+	data, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename+ModulesExt, data, 0644)
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/apiextensions"
+)
+
+// PendingExt is the extension of the journal of a pending registration. It's
+// kept next to the coredump file in host cache, e.g. "<file>.pending.json",
+// so the file isn't removed as orphaned until its object is created.
+const PendingExt = ".pending.json"
+
+const (
+	// pendingBackoff is the delay before the first retry of a pending
+	// registration, it's doubled after each failure up to pendingMaxBackoff.
+	pendingBackoff    = 15 * time.Second
+	pendingMaxBackoff = 15 * time.Minute
+)
+
+// pending is a Coredump object which failed to be registered to the
+// apiserver, e.g. the apiserver is unreachable or the token is expired when
+// the coredump happens. It's retried by coredump-agent with backoff.
+type pending struct {
+	Namespace string
+	// Coredump is the object to create. If Created is true, it's the object
+	// created whose status is not set yet.
+	Coredump *coredump.Coredump
+	Created  bool
+	// Attempts is the number of failed attempts.
+	Attempts    int
+	LastError   string
+	NextAttempt time.Time
+}
+
+// register creates the Coredump object and sets its status. If the
+// apiserver fails, the object is kept in the journal, and the coredump is
+// registered later by ReplayPending of coredump-agent. Without the agent,
+// nothing replays the journal, so the error is returned.
+func register(cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, cd *coredump.Coredump, namespace string) error {
+	created, err := createCoredump(cc, cdo, cd, namespace)
+	if err == nil || !retriable(err) || cdo.AgentSocket == "" {
+		return err
+	}
+	p := &pending{Namespace: namespace, Coredump: cd}
+	if created != nil {
+		p.Coredump = created
+		p.Created = true
+	}
+	p.failed(err)
+	if journalErr := writePending(pendingPath(cdo.DumpDir, namespace, p.Coredump), p); journalErr != nil {
+		glog.Errorf("Failed to write the journal of coredump %s/%s: %v", namespace, cd.ObjectMeta.Name, journalErr)
+		return err
+	}
+	glog.Warningf("Failed to register coredump %s/%s, retry it at %s: %v", namespace, cd.ObjectMeta.Name, p.NextAttempt.Format(time.RFC3339), err)
+	return nil
+}
+
+// retriable returns false if the apiserver rejects the object, which never
+// succeeds by retrying.
+func retriable(err error) bool {
+	return !apierrors.IsAlreadyExists(err) && !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err)
+}
+
+// failed records the failed attempt, and schedules the next one.
+func (p *pending) failed(err error) {
+	backoff := pendingMaxBackoff
+	if p.Attempts < 10 {
+		if b := pendingBackoff << uint(p.Attempts); b < backoff {
+			backoff = b
+		}
+	}
+	p.Attempts++
+	p.LastError = err.Error()
+	p.NextAttempt = time.Now().Add(backoff)
+}
+
+// pendingPath returns the path of the journal of the Coredump object, which
+// may have no namespace set yet.
+func pendingPath(dumpDir, namespace string, cd *coredump.Coredump) string {
+	dirname := CacheDir(namespace, cd.Spec.Pod, string(cd.Spec.Uid), cd.Spec.ContainerName)
+	return filepath.Join(dumpDir, dirname, cd.ObjectMeta.Name+compressionExts[cd.Spec.Compression]+PendingExt)
+}
+
+// writePending writes the journal, it's replaced as a whole.
+func writePending(filename string, p *pending) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0775); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+func readPending(filename string) (*pending, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &pending{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReplayPending retries the pending registrations in host cache which are
+// due. The Coredump objects keep the time when the coredumps happened, and
+// objects created late are annotated with the delay.
+func ReplayPending(cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions) {
+	now := time.Now()
+	err := filepath.Walk(cdo.DumpDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(path, PendingExt) {
+			return nil
+		}
+		p, err := readPending(path)
+		if err != nil {
+			glog.Errorf("Invalid journal %s, removed: %v", path, err)
+			os.Remove(path)
+			return nil
+		}
+		if now.Before(p.NextAttempt) {
+			return nil
+		}
+		if err := replayPending(cc, cdo, p, strings.TrimSuffix(path, PendingExt)); err != nil {
+			if retriable(err) {
+				p.failed(err)
+				glog.Warningf("Failed to register coredump %s/%s, retry it at %s: %v", p.Namespace, p.Coredump.ObjectMeta.Name, p.NextAttempt.Format(time.RFC3339), err)
+				if err := writePending(path, p); err != nil {
+					glog.Errorf("Failed to write the journal %s: %v", path, err)
+				}
+				return nil
+			}
+			glog.Errorf("Failed to register coredump %s/%s, gave up after %d attempts: %v", p.Namespace, p.Coredump.ObjectMeta.Name, p.Attempts+1, err)
+		}
+		os.Remove(path)
+		return nil
+	})
+	if err != nil {
+		glog.Errorf("Failed to walk %s: %v", cdo.DumpDir, err)
+	}
+}
+
+// replayPending registers the Coredump object of the journal. The coredump
+// file is at filename, if the object has one.
+func replayPending(cc apiextensions.CoredumpClient, cdo *options.CoredumpDetectorOptions, p *pending, filename string) error {
+	cd := p.Coredump
+	if p.Created {
+		err := updatePendingStatus(cc, p.Namespace, cd)
+		if apierrors.IsNotFound(err) {
+			// the object is deleted meanwhile.
+			glog.Warningf("Status of coredump %s/%s is not set: %v", p.Namespace, cd.ObjectMeta.Name, err)
+			return nil
+		}
+		return err
+	}
+	if hasFile(cd) {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			glog.Warningf("Coredump file of %s/%s is removed, not registered", p.Namespace, cd.ObjectMeta.Name)
+			return nil
+		}
+	}
+	delay := time.Since(cd.Spec.Time.Time).Round(time.Second)
+	if cd.ObjectMeta.Annotations == nil {
+		cd.ObjectMeta.Annotations = map[string]string{}
+	}
+	cd.ObjectMeta.Annotations[coredump.RegistrationDelayAnnotation] = delay.String()
+	created, err := createCoredump(cc, cdo, cd, p.Namespace)
+	if err != nil && created != nil {
+		// only the status is retried.
+		p.Coredump = created
+		p.Created = true
+	}
+	if err == nil {
+		glog.Infof("Registered coredump %s/%s %s after it happened", p.Namespace, cd.ObjectMeta.Name, delay)
+	}
+	return err
+}
+
+// updatePendingStatus sets the status of the journal on the Coredump object.
+// The object in the journal is likely stale, e.g. it's updated by
+// coredump-saver, coredump-controller or the garbage collector meanwhile, so
+// the status is applied to the latest object on conflict.
+func updatePendingStatus(cc apiextensions.CoredumpClient, namespace string, cd *coredump.Coredump) error {
+	obj := cd
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		_, err := cc.UpdateCoredumpStatus(obj)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+		latest, getErr := cc.GetCoredump(namespace, cd.ObjectMeta.Name)
+		if getErr != nil {
+			return getErr
+		}
+		latest.Status = cd.Status
+		obj = latest
+		return err
+	})
+}
+
+// hasFile returns true if the Coredump object has a file in host cache.
+func hasFile(cd *coredump.Coredump) bool {
+	for _, f := range cd.ObjectMeta.Finalizers {
+		if f == coredump.FileCleanupFinalizer {
+			return true
+		}
+	}
+	return false
+}
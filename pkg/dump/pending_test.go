@@ -0,0 +1,150 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+)
+
+func TestPendingBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 15 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{6, 15 * time.Minute},
+		{10, 15 * time.Minute},
+		// the shift doesn't overflow after many attempts.
+		{100, 15 * time.Minute},
+	} {
+		p := &pending{Attempts: tc.attempts}
+		before := time.Now()
+		p.failed(errors.New("unreachable"))
+		if got := p.NextAttempt.Sub(before); got < tc.want || got > tc.want+time.Second {
+			t.Errorf("backoff after %d attempts = %s, want %s", tc.attempts, got, tc.want)
+		}
+		if p.Attempts != tc.attempts+1 || p.LastError != "unreachable" {
+			t.Errorf("after %d attempts: attempts = %d, last error %q", tc.attempts, p.Attempts, p.LastError)
+		}
+	}
+}
+
+func newPendingCoredump(name string) *coredump.Coredump {
+	return &coredump.Coredump{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: coredump.CoredumpSpec{
+			Pod:           "web",
+			Uid:           "uid",
+			ContainerName: "app",
+			Time:          metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Status: coredump.CoredumpStatus{State: coredump.CoredumpStateCreated},
+	}
+}
+
+func TestReplayPending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cdo := &options.CoredumpDetectorOptions{DumpDir: dir}
+
+	due := &pending{Namespace: "default", Coredump: newPendingCoredump("due"), Attempts: 1, NextAttempt: time.Now().Add(-time.Second)}
+	notDue := &pending{Namespace: "default", Coredump: newPendingCoredump("not-due"), Attempts: 3, NextAttempt: time.Now().Add(time.Minute)}
+	for _, p := range []*pending{due, notDue} {
+		if err := writePending(pendingPath(dir, p.Namespace, p.Coredump), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := newFakeCoredumpClient()
+	ReplayPending(client, cdo)
+
+	cd, err := client.GetCoredump("default", "due")
+	if err != nil {
+		t.Fatalf("coredump due is not registered: %v", err)
+	}
+	if cd.Status.State != coredump.CoredumpStateCreated || cd.ObjectMeta.Annotations[coredump.RegistrationDelayAnnotation] == "" {
+		t.Errorf("registered coredump = %+v, want the status and the registration delay", cd)
+	}
+	if _, err := os.Stat(pendingPath(dir, "default", due.Coredump)); !os.IsNotExist(err) {
+		t.Errorf("journal of the registered coredump is kept: %v", err)
+	}
+	if _, err := client.GetCoredump("default", "not-due"); !apierrors.IsNotFound(err) {
+		t.Errorf("coredump not due is registered: %v", err)
+	}
+	p, err := readPending(pendingPath(dir, "default", notDue.Coredump))
+	if err != nil || p.Attempts != 3 {
+		t.Errorf("journal of the coredump not due = %+v, %v, want it unchanged", p, err)
+	}
+}
+
+func TestReplayPendingFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cdo := &options.CoredumpDetectorOptions{DumpDir: dir}
+
+	retried := &pending{Namespace: "default", Coredump: newPendingCoredump("retried"), Attempts: 2}
+	path := pendingPath(dir, retried.Namespace, retried.Coredump)
+	if err := writePending(path, retried); err != nil {
+		t.Fatal(err)
+	}
+	client := newFakeCoredumpClient()
+	client.err = apierrors.NewServerTimeout(coredump.Resource("coredumps"), "create", 1)
+
+	before := time.Now()
+	ReplayPending(client, cdo)
+
+	p, err := readPending(path)
+	if err != nil {
+		t.Fatalf("journal of the failed coredump is removed: %v", err)
+	}
+	if p.Attempts != 3 || p.LastError == "" {
+		t.Errorf("attempts = %d, last error %q, want 3 attempts and the error", p.Attempts, p.LastError)
+	}
+	if got := p.NextAttempt.Sub(before); got < time.Minute || got > time.Minute+time.Second {
+		t.Errorf("next attempt in %s, want 1m0s", got)
+	}
+
+	// the apiserver rejects the object, it's never retried.
+	client.err = apierrors.NewBadRequest("invalid coredump")
+	p.NextAttempt = time.Time{}
+	if err := writePending(path, p); err != nil {
+		t.Fatal(err)
+	}
+	ReplayPending(client, cdo)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("journal of the rejected coredump is kept: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/kube"
+	"k8s.io/coredump-detector/pkg/ratelimit"
+	"k8s.io/coredump-detector/pkg/resolver"
+)
+
+// othersKeyPrefix is the prefix of rate limit keys of processes not in pods,
+// which are limited by executable.
+const othersKeyPrefix = "others/"
+
+// ValidateRateLimit checks the rate limit options.
+func ValidateRateLimit(cdo *options.CoredumpDetectorOptions) error {
+	if cdo.ContainerRateLimit < 0 || cdo.NodeRateLimit < 0 {
+		return fmt.Errorf("invalid rate limit: must not be negative")
+	}
+	if cdo.ContainerRateLimit > 0 && cdo.ContainerBurst < 1 {
+		return fmt.Errorf("invalid container burst %d: must be positive", cdo.ContainerBurst)
+	}
+	if cdo.NodeRateLimit > 0 && cdo.NodeBurst < 1 {
+		return fmt.Errorf("invalid node burst %d: must be positive", cdo.NodeBurst)
+	}
+	return nil
+}
+
+// NewRequest returns the request to handle the coredump of the process. It
+// reads the container of the process from its cgroup, and checks the rate
+// limit of the container and the node, so that coredumps dropped don't call
+// the container runtime or the apiserver. Processes not in pods are limited
+// by executable. It returns nil if the coredump is dropped, the coredump is
+// handled if the rate limit fails to be checked.
+func NewRequest(progressInfo *options.ProgressInfo, cdo *options.CoredumpDetectorOptions) *Request {
+	req := &Request{ProgressInfo: *progressInfo}
+	if progressInfo.Pidfd != "" {
+		checkPidfd(&req.ProgressInfo)
+	}
+	// the process is alive until its coredump is read.
+	if cmdline, err := resolver.ReadCmdline(req.HostPid); err == nil {
+		req.Cmdline = cmdline
+	}
+	key := othersKeyPrefix + progressInfo.Filename
+	if progressInfo.ContainerPid != progressInfo.HostPid {
+		containerID, err := resolver.ContainerID(progressInfo.HostPid)
+		if err != nil {
+			glog.Infof("failed to parse cgroup of process %s, limited by executable: %v", progressInfo.HostPid, err)
+		} else if containerID != "" {
+			key = containerID
+			req.ContainerID = containerID
+		}
+	}
+	decision := takeToken(key, cdo)
+	if !decision.Allowed {
+		glog.Infof("Dropped coredump of %s (pid %s), rate limit exceeded", progressInfo.Filename, progressInfo.HostPid)
+		return nil
+	}
+	if decision.Suppressed > 0 {
+		req.Suppressed = decision.Suppressed
+		req.FirstSuppressed = &decision.FirstSuppressed
+		glog.Warningf("Coredump of %s (pid %s) is handled, %s", progressInfo.Filename, progressInfo.HostPid, req.suppressedMessage())
+	}
+	return req
+}
+
+// checkPidfd checks the PID of dumped process with its pidfd, the PID read
+// from the pidfd is used if they differ.
+func checkPidfd(progressInfo *options.ProgressInfo) {
+	pidfd, err := strconv.Atoi(progressInfo.Pidfd)
+	if err != nil {
+		glog.Errorf("Invalid pidfd %q: %v", progressInfo.Pidfd, err)
+		return
+	}
+	pid, err := resolver.PidfdPid(pidfd)
+	if err != nil {
+		glog.Errorf("Failed to read pidfd %d: %v", pidfd, err)
+		return
+	}
+	if pid <= 0 {
+		glog.Warningf("Process of pidfd %d is not visible, use PID %s", pidfd, progressInfo.HostPid)
+		return
+	}
+	if strconv.Itoa(pid) != progressInfo.HostPid {
+		glog.Warningf("PID %s doesn't match PID %d of pidfd %d, use PID %d", progressInfo.HostPid, pid, pidfd, pid)
+		progressInfo.HostPid = strconv.Itoa(pid)
+	}
+}
+
+func newLimiter(cdo *options.CoredumpDetectorOptions) *ratelimit.Limiter {
+	return &ratelimit.Limiter{
+		StateFile: cdo.RateLimitState,
+		Key:       ratelimit.Limit{PerMinute: cdo.ContainerRateLimit, Burst: cdo.ContainerBurst},
+		Node:      ratelimit.Limit{PerMinute: cdo.NodeRateLimit, Burst: cdo.NodeBurst},
+	}
+}
+
+// takeToken takes a token of the rate limit of key and the node.
+func takeToken(key string, cdo *options.CoredumpDetectorOptions) *ratelimit.Decision {
+	decision, err := newLimiter(cdo).Take(key, time.Now())
+	if err != nil {
+		glog.Errorf("Failed to check rate limit: %v", err)
+		return &ratelimit.Decision{Allowed: true}
+	}
+	return decision
+}
+
+// ReportSuppressed reports coredumps dropped by the rate limit whose
+// suppression window is closed, without waiting for a later coredump of the
+// container to be handled. A CoredumpsSuppressed event is recorded on the pod
+// of the container, even if the pod is deleted meanwhile. It's called
+// periodically by coredump-agent in the node nodeName.
+func ReportSuppressed(kc kube.Client, runtime resolver.RuntimeResolver, cdo *options.CoredumpDetectorOptions, nodeName string) {
+	summaries, err := newLimiter(cdo).Flush(time.Now())
+	if err != nil {
+		glog.Errorf("Failed to flush rate limit: %v", err)
+		return
+	}
+	for _, s := range summaries {
+		message := fmt.Sprintf("%d coredumps were dropped by the rate limit from %s to %s",
+			s.Suppressed, s.FirstSuppressed.Format(time.RFC3339), s.LastSuppressed.Format(time.RFC3339))
+		if strings.HasPrefix(s.Key, othersKeyPrefix) {
+			glog.Warningf("Coredumps of %s are handled at a limited rate, %s", strings.TrimPrefix(s.Key, othersKeyPrefix), message)
+			continue
+		}
+		container, err := runtime.ResolveID(s.Key)
+		if err != nil || container == nil {
+			glog.Warningf("Coredumps of container %s are handled at a limited rate, %s", s.Key, message)
+			continue
+		}
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      container.Pod,
+				Namespace: container.Namespace,
+				UID:       apitypes.UID(container.Uid),
+			},
+			Spec: v1.PodSpec{NodeName: nodeName},
+		}
+		message = fmt.Sprintf("Coredumps of container %s are handled at a limited rate, %s", container.ContainerName, message)
+		glog.Warningf("Pod %s/%s: %s", pod.ObjectMeta.Namespace, pod.ObjectMeta.Name, message)
+		if err := recordPodEvent(kc, pod, v1.EventTypeWarning, "CoredumpsSuppressed", message); err != nil {
+			glog.Errorf("Failed to record event: %v", err)
+		}
+	}
+}
+
+// suppressedMessage describes the coredumps dropped before the one handled.
+func (req *Request) suppressedMessage() string {
+	return fmt.Sprintf("%d coredumps were dropped by the rate limit since %s",
+		req.Suppressed, req.FirstSuppressed.Format(time.RFC3339))
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elfcore
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// ntGNUBuildID is the type of the build-id note with name "GNU".
+const ntGNUBuildID = 3
+
+// BuildID returns the GNU build-id of the ELF file in hex. It's read from
+// the notes of the program headers, or of the sections if the file has no
+// program headers, e.g. a debug file.
+func BuildID(r io.ReaderAt) (string, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		if id := findBuildID(prog.Open(), f.ByteOrder); id != "" {
+			return id, nil
+		}
+	}
+	for _, section := range f.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		if id := findBuildID(section.Open(), f.ByteOrder); id != "" {
+			return id, nil
+		}
+	}
+	return "", errors.New("no build-id in ELF file")
+}
+
+// findBuildID returns the build-id in the notes, empty if there is none.
+func findBuildID(r io.Reader, byteOrder binary.ByteOrder) string {
+	buf, err := ioutil.ReadAll(io.LimitReader(r, maxNotesSize))
+	if err != nil {
+		return ""
+	}
+	for len(buf) >= 12 {
+		namesz := int64(byteOrder.Uint32(buf))
+		descsz := int64(byteOrder.Uint32(buf[4:]))
+		typ := byteOrder.Uint32(buf[8:])
+		nameEnd := 12 + align4(namesz)
+		if nameEnd+descsz > int64(len(buf)) {
+			return ""
+		}
+		name := string(bytes.TrimRight(buf[12:12+namesz], "\x00"))
+		if name == "GNU" && typ == ntGNUBuildID {
+			return hex.EncodeToString(buf[nameEnd : nameEnd+descsz])
+		}
+		descEnd := nameEnd + align4(descsz)
+		if descEnd > int64(len(buf)) {
+			return ""
+		}
+		buf = buf[descEnd:]
+	}
+	return ""
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elfcore
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Types of notes written by the kernel with name "CORE".
+const (
+	ntPrstatus = 1
+	ntPrpsinfo = 3
+	ntAuxv     = 6
+	ntSiginfo  = 0x53494749
+	ntFile     = 0x46494c45
+)
+
+// Notes is what's read from the notes of an ELF core file.
+type Notes struct {
+	// Architecture is the machine of the process, e.g. "x86_64".
+	Architecture string
+	// Signal is the signal which caused the coredump, and SignalCode is
+	// its si_code.
+	Signal     int
+	SignalCode int
+	// FaultAddress is the address which caused the fault, it's only set
+	// for signals like SIGSEGV and SIGBUS.
+	FaultAddress *uint64
+	// TID is the thread which caused the coredump, as seen in the PID
+	// namespace of the process. Its status is the first NT_PRSTATUS.
+	TID int
+	// Registers are the general purpose registers of the thread, in the
+	// order of the kernel. They're only read for known architectures,
+	// which are x86_64, i386 and aarch64, and empty for other machines.
+	Registers []Register
+	// Fname is the executable filename, and Psargs is the command line
+	// truncated to 80 bytes.
+	Fname  string
+	Psargs string
+	// Auxv is the auxiliary vector of the process.
+	Auxv map[uint64]uint64
+	// Files are the files mapped in the process.
+	Files []File
+}
+
+// Register is a register of a thread.
+type Register struct {
+	Name  string
+	Value uint64
+}
+
+// File is a file mapped in the process. Offset is in bytes.
+type File struct {
+	Start  uint64
+	End    uint64
+	Offset uint64
+	Path   string
+}
+
+// parseNotes parses the notes in buf.
+func (p *Parser) parseNotes() (*Notes, error) {
+	notes := &Notes{Architecture: architecture(p.machine, p.class, p.byteOrder == binary.BigEndian)}
+	var siginfo []byte
+	prstatus := false
+	buf := p.buf
+	for len(buf) >= 12 {
+		namesz := int64(p.byteOrder.Uint32(buf))
+		descsz := int64(p.byteOrder.Uint32(buf[4:]))
+		typ := p.byteOrder.Uint32(buf[8:])
+		nameEnd := 12 + align4(namesz)
+		descEnd := nameEnd + align4(descsz)
+		if nameEnd+descsz > int64(len(buf)) {
+			return nil, fmt.Errorf("invalid note of type %#x", typ)
+		}
+		name := string(bytes.TrimRight(buf[12:12+namesz], "\x00"))
+		desc := buf[nameEnd : nameEnd+descsz]
+		if descEnd > int64(len(buf)) {
+			descEnd = int64(len(buf))
+		}
+		buf = buf[descEnd:]
+		if name != "CORE" {
+			continue
+		}
+		switch typ {
+		case ntPrstatus:
+			// the thread which caused the coredump is the first.
+			if !prstatus {
+				prstatus = true
+				p.parsePrstatus(notes, desc)
+			}
+		case ntPrpsinfo:
+			// pr_fname[16] and pr_psargs[80] are at the end.
+			if len(desc) >= 96 {
+				notes.Fname = cString(desc[len(desc)-96 : len(desc)-80])
+				notes.Psargs = strings.TrimRight(cString(desc[len(desc)-80:]), " ")
+			}
+		case ntSiginfo:
+			siginfo = desc
+		case ntAuxv:
+			notes.Auxv = map[uint64]uint64{}
+			words := p.words(desc)
+			for i := 0; i+1 < len(words) && words[i] != 0; i += 2 {
+				notes.Auxv[words[i]] = words[i+1]
+			}
+		case ntFile:
+			notes.Files = p.parseFiles(desc)
+		}
+	}
+	if siginfo != nil {
+		p.parseSiginfo(notes, siginfo)
+	}
+	return notes, nil
+}
+
+// parsePrstatus parses struct elf_prstatus.
+func (p *Parser) parsePrstatus(notes *Notes, desc []byte) {
+	pidOff, regOff := 32, 112
+	if p.class == elf.ELFCLASS32 {
+		pidOff, regOff = 24, 72
+	}
+	if len(desc) < regOff {
+		return
+	}
+	// pr_cursig is only used if there is no NT_SIGINFO.
+	notes.Signal = int(int16(p.byteOrder.Uint16(desc[12:])))
+	notes.TID = int(int32(p.byteOrder.Uint32(desc[pidOff:])))
+	names := registerNames[p.machine]
+	regs := p.words(desc[regOff:])
+	if len(regs) < len(names) {
+		return
+	}
+	for i, name := range names {
+		notes.Registers = append(notes.Registers, Register{Name: name, Value: regs[i]})
+	}
+}
+
+// parseSiginfo parses siginfo_t.
+func (p *Parser) parseSiginfo(notes *Notes, desc []byte) {
+	addrOff := 16
+	if p.class == elf.ELFCLASS32 {
+		addrOff = 12
+	}
+	if len(desc) < addrOff+p.wordSize() {
+		return
+	}
+	notes.Signal = int(int32(p.byteOrder.Uint32(desc)))
+	notes.SignalCode = int(int32(p.byteOrder.Uint32(desc[8:])))
+	// si_code <= 0 means the signal is sent by a process, e.g. kill(2).
+	if faultSignals[notes.Signal] && notes.SignalCode > 0 {
+		addr := p.words(desc[addrOff:])[0]
+		notes.FaultAddress = &addr
+	}
+}
+
+// parseFiles parses NT_FILE: the number of files, the page size, (start,
+// end, page offset) of each file, and then the paths.
+func (p *Parser) parseFiles(desc []byte) []File {
+	words := p.words(desc)
+	if len(words) < 2 {
+		return nil
+	}
+	count, pageSize := words[0], words[1]
+	if count > uint64(len(words)-2)/3 {
+		return nil
+	}
+	paths := bytes.Split(desc[(2+3*count)*uint64(p.wordSize()):], []byte{0})
+	if uint64(len(paths)) < count {
+		return nil
+	}
+	files := make([]File, 0, count)
+	for i := uint64(0); i < count; i++ {
+		files = append(files, File{
+			Start:  words[2+3*i],
+			End:    words[3+3*i],
+			Offset: words[4+3*i] * pageSize,
+			Path:   string(paths[i]),
+		})
+	}
+	return files
+}
+
+func (p *Parser) wordSize() int {
+	if p.class == elf.ELFCLASS32 {
+		return 4
+	}
+	return 8
+}
+
+// words reads b as an array of unsigned long.
+func (p *Parser) words(b []byte) []uint64 {
+	size := p.wordSize()
+	words := make([]uint64, 0, len(b)/size)
+	for ; len(b) >= size; b = b[size:] {
+		if size == 4 {
+			words = append(words, uint64(p.byteOrder.Uint32(b)))
+		} else {
+			words = append(words, p.byteOrder.Uint64(b))
+		}
+	}
+	return words
+}
+
+func align4(n int64) int64 {
+	return (n + 3) &^ 3
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// architecture returns the name of the machine as uname -m.
+func architecture(machine elf.Machine, class elf.Class, bigEndian bool) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return "x86_64"
+	case elf.EM_386:
+		return "i386"
+	case elf.EM_AARCH64:
+		return "aarch64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if bigEndian {
+			return "ppc64"
+		}
+		return "ppc64le"
+	case elf.EM_S390:
+		if class == elf.ELFCLASS64 {
+			return "s390x"
+		}
+		return "s390"
+	case elf.EM_RISCV:
+		if class == elf.ELFCLASS64 {
+			return "riscv64"
+		}
+		return "riscv32"
+	}
+	return strings.ToLower(strings.TrimPrefix(machine.String(), "EM_"))
+}
+
+// registerNames are the names of registers in elf_gregset_t, which is
+// struct user_regs_struct of the architecture.
+var registerNames = map[elf.Machine][]string{
+	elf.EM_X86_64: {
+		"r15", "r14", "r13", "r12", "rbp", "rbx", "r11", "r10", "r9", "r8",
+		"rax", "rcx", "rdx", "rsi", "rdi", "orig_rax", "rip", "cs", "eflags",
+		"rsp", "ss", "fs_base", "gs_base", "ds", "es", "fs", "gs",
+	},
+	elf.EM_386: {
+		"ebx", "ecx", "edx", "esi", "edi", "ebp", "eax", "ds", "es", "fs",
+		"gs", "orig_eax", "eip", "cs", "eflags", "esp", "ss",
+	},
+	elf.EM_AARCH64: aarch64Registers(),
+}
+
+func aarch64Registers() []string {
+	names := make([]string, 0, 34)
+	for i := 0; i < 31; i++ {
+		names = append(names, "x"+strconv.Itoa(i))
+	}
+	return append(names, "sp", "pc", "pstate")
+}
+
+// faultSignals are the signals whose si_addr is the address of the fault.
+var faultSignals = map[int]bool{4: true, 5: true, 7: true, 8: true, 11: true}
+
+// signalNames are the names of signals of Linux.
+var signalNames = []string{
+	1: "SIGHUP", 2: "SIGINT", 3: "SIGQUIT", 4: "SIGILL", 5: "SIGTRAP",
+	6: "SIGABRT", 7: "SIGBUS", 8: "SIGFPE", 9: "SIGKILL", 10: "SIGUSR1",
+	11: "SIGSEGV", 12: "SIGUSR2", 13: "SIGPIPE", 14: "SIGALRM", 15: "SIGTERM",
+	16: "SIGSTKFLT", 17: "SIGCHLD", 18: "SIGCONT", 19: "SIGSTOP", 20: "SIGTSTP",
+	21: "SIGTTIN", 22: "SIGTTOU", 23: "SIGURG", 24: "SIGXCPU", 25: "SIGXFSZ",
+	26: "SIGVTALRM", 27: "SIGPROF", 28: "SIGWINCH", 29: "SIGIO", 30: "SIGPWR",
+	31: "SIGSYS",
+}
+
+// SignalName returns the name of the signal of Linux, e.g. "SIGSEGV".
+func SignalName(signal int) string {
+	if signal > 0 && signal < len(signalNames) {
+		return signalNames[signal]
+	}
+	if signal >= 34 && signal <= 64 {
+		return "SIGRTMIN+" + strconv.Itoa(signal-34)
+	}
+	return "SIG" + strconv.Itoa(signal)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package elfcore parses the notes of ELF core files while they're streamed,
+// so the coredump is read only once and not buffered in memory. The kernel
+// writes the notes right after the program headers, so only the beginning
+// of the coredump is kept.
+package elfcore
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// maxNotesSize is the max size of the notes kept. NT_FILE of a process with
+// many mappings is large, but still far less than it.
+const maxNotesSize = 16 << 20
+
+// Parser is a writer which parses the notes of the ELF core file written to
+// it. It never fails to write, so it can be used with io.TeeReader.
+type Parser struct {
+	// buf is the beginning of the file until the program headers are parsed,
+	// and then the notes.
+	buf []byte
+	// off is the offset of the next byte written.
+	off int64
+	// need is the size of buf needed to parse the headers.
+	need int64
+
+	class     elf.Class
+	byteOrder binary.ByteOrder
+	machine   elf.Machine
+	// notesOff and notesEnd are the range of PT_NOTE in the file, notesOff
+	// is -1 until the program headers are parsed.
+	notesOff int64
+	notesEnd int64
+
+	notes *Notes
+	err   error
+
+	// OnNotes is called once the notes are parsed, while the rest of the
+	// file is still being written, e.g. to read /proc of the process before
+	// it exits.
+	OnNotes func(*Notes)
+}
+
+// NewParser returns a parser of an ELF core file.
+func NewParser() *Parser {
+	return &Parser{need: elf.EI_NIDENT, notesOff: -1}
+}
+
+// Write parses p as the next bytes of the file.
+func (p *Parser) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 && p.notes == nil && p.err == nil {
+		if p.notesOff < 0 {
+			take := p.need - int64(len(p.buf))
+			if take > int64(len(b)) {
+				take = int64(len(b))
+			}
+			p.buf = append(p.buf, b[:take]...)
+			b = b[take:]
+			p.off += take
+			if int64(len(p.buf)) == p.need {
+				p.err = p.parseHeaders()
+			}
+			if p.err == nil && p.notesOff >= 0 && p.off >= p.notesEnd {
+				// the notes are in the headers already read.
+				p.finish()
+			}
+			continue
+		}
+		if p.off < p.notesOff {
+			skip := p.notesOff - p.off
+			if skip > int64(len(b)) {
+				skip = int64(len(b))
+			}
+			b = b[skip:]
+			p.off += skip
+			continue
+		}
+		take := p.notesEnd - p.off
+		if take > int64(len(b)) {
+			take = int64(len(b))
+		}
+		p.buf = append(p.buf, b[:take]...)
+		b = b[take:]
+		p.off += take
+		if p.off == p.notesEnd {
+			p.finish()
+		}
+	}
+	return n, nil
+}
+
+// Notes returns the notes parsed, or an error if the file is not an ELF
+// core file, or ends before its notes.
+func (p *Parser) Notes() (*Notes, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.notes == nil {
+		return nil, errors.New("unexpected end of ELF core file")
+	}
+	return p.notes, nil
+}
+
+// finish parses the notes in buf.
+func (p *Parser) finish() {
+	p.notes, p.err = p.parseNotes()
+	p.buf = nil
+	if p.err == nil && p.OnNotes != nil {
+		p.OnNotes(p.notes)
+	}
+}
+
+// parseHeaders parses the ELF header and the program headers in buf. If buf
+// isn't large enough, need is raised.
+func (p *Parser) parseHeaders() error {
+	ident := p.buf[:elf.EI_NIDENT]
+	if string(ident[:4]) != elf.ELFMAG {
+		return errors.New("not an ELF file")
+	}
+	p.class = elf.Class(ident[elf.EI_CLASS])
+	switch elf.Data(ident[elf.EI_DATA]) {
+	case elf.ELFDATA2LSB:
+		p.byteOrder = binary.LittleEndian
+	case elf.ELFDATA2MSB:
+		p.byteOrder = binary.BigEndian
+	default:
+		return fmt.Errorf("unknown ELF data encoding %d", ident[elf.EI_DATA])
+	}
+
+	var ehsize, phsize int64
+	switch p.class {
+	case elf.ELFCLASS32:
+		ehsize, phsize = 52, 32
+	case elf.ELFCLASS64:
+		ehsize, phsize = 64, 56
+	default:
+		return fmt.Errorf("unknown ELF class %d", ident[elf.EI_CLASS])
+	}
+	if int64(len(p.buf)) < ehsize {
+		p.need = ehsize
+		return nil
+	}
+
+	var phoff int64
+	var phentsize, phnum uint16
+	var typ elf.Type
+	if p.class == elf.ELFCLASS32 {
+		var hdr elf.Header32
+		binary.Read(bytes.NewReader(p.buf), p.byteOrder, &hdr)
+		typ, p.machine = elf.Type(hdr.Type), elf.Machine(hdr.Machine)
+		phoff, phentsize, phnum = int64(hdr.Phoff), hdr.Phentsize, hdr.Phnum
+	} else {
+		var hdr elf.Header64
+		binary.Read(bytes.NewReader(p.buf), p.byteOrder, &hdr)
+		typ, p.machine = elf.Type(hdr.Type), elf.Machine(hdr.Machine)
+		phoff, phentsize, phnum = int64(hdr.Phoff), hdr.Phentsize, hdr.Phnum
+	}
+	if typ != elf.ET_CORE {
+		return fmt.Errorf("not an ELF core file, type %s", typ)
+	}
+	if int64(phentsize) < phsize {
+		return fmt.Errorf("invalid size of program header %d", phentsize)
+	}
+	if phnum == 0xffff {
+		// PN_XNUM, the number is in the section header at the end.
+		return errors.New("too many program headers")
+	}
+	end := phoff + int64(phnum)*int64(phentsize)
+	if phoff < ehsize || end > maxNotesSize {
+		return fmt.Errorf("invalid offset of program headers %d", phoff)
+	}
+	if int64(len(p.buf)) < end {
+		p.need = end
+		return nil
+	}
+
+	for i := int64(0); i < int64(phnum); i++ {
+		ph := p.buf[phoff+i*int64(phentsize):]
+		var typ elf.ProgType
+		var off, size int64
+		if p.class == elf.ELFCLASS32 {
+			var prog elf.Prog32
+			binary.Read(bytes.NewReader(ph), p.byteOrder, &prog)
+			typ, off, size = elf.ProgType(prog.Type), int64(prog.Off), int64(prog.Filesz)
+		} else {
+			var prog elf.Prog64
+			binary.Read(bytes.NewReader(ph), p.byteOrder, &prog)
+			typ, off, size = elf.ProgType(prog.Type), int64(prog.Off), int64(prog.Filesz)
+		}
+		if typ != elf.PT_NOTE {
+			continue
+		}
+		if off < 0 || size < 0 || size > maxNotesSize {
+			return fmt.Errorf("invalid PT_NOTE at %d of %d bytes", off, size)
+		}
+		p.notesOff, p.notesEnd = off, off+size
+		// keep the part of notes already read.
+		if off < p.off {
+			keepEnd := p.notesEnd
+			if keepEnd > p.off {
+				keepEnd = p.off
+			}
+			p.buf = append([]byte(nil), p.buf[off:keepEnd]...)
+		} else {
+			p.buf = nil
+		}
+		return nil
+	}
+	return errors.New("no PT_NOTE in ELF core file")
+}
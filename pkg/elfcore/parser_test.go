@@ -0,0 +1,339 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elfcore
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// note is a note of a synthetic ELF file.
+type note struct {
+	name string
+	typ  uint32
+	desc []byte
+}
+
+// testELF describes a synthetic ELF file with a PT_NOTE segment.
+type testELF struct {
+	class   elf.Class
+	order   binary.ByteOrder
+	machine elf.Machine
+	typ     elf.Type
+	notes   []note
+	// gap is the number of bytes between the program headers and the notes.
+	gap int
+	// noteSize overrides the size of PT_NOTE if it's positive.
+	noteSize uint64
+	// tail is appended after the notes, like PT_LOAD segments of a core.
+	tail []byte
+}
+
+// encodeNotes encodes the notes, names and descriptors are padded to 4
+// bytes.
+func encodeNotes(order binary.ByteOrder, notes []note) []byte {
+	var buf bytes.Buffer
+	for _, n := range notes {
+		name := []byte(n.name)
+		if n.name != "" {
+			name = append(name, 0)
+		}
+		binary.Write(&buf, order, uint32(len(name)))
+		binary.Write(&buf, order, uint32(len(n.desc)))
+		binary.Write(&buf, order, n.typ)
+		buf.Write(pad4(name))
+		buf.Write(pad4(n.desc))
+	}
+	return buf.Bytes()
+}
+
+func pad4(b []byte) []byte {
+	return append(append([]byte(nil), b...), make([]byte, align4(int64(len(b)))-int64(len(b)))...)
+}
+
+func (e testELF) bytes() []byte {
+	if e.class == 0 {
+		e.class = elf.ELFCLASS64
+	}
+	if e.order == nil {
+		e.order = binary.LittleEndian
+	}
+	if e.machine == 0 {
+		e.machine = elf.EM_X86_64
+	}
+	if e.typ == 0 {
+		e.typ = elf.ET_CORE
+	}
+	notes := encodeNotes(e.order, e.notes)
+	noteSize := uint64(len(notes))
+	if e.noteSize > 0 {
+		noteSize = e.noteSize
+	}
+
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(e.class)
+	ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	if e.order == binary.BigEndian {
+		ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+	}
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	if e.class == elf.ELFCLASS32 {
+		binary.Write(&buf, e.order, elf.Header32{
+			Ident: ident, Type: uint16(e.typ), Machine: uint16(e.machine), Version: uint32(elf.EV_CURRENT),
+			Phoff: 52, Ehsize: 52, Phentsize: 32, Phnum: 1,
+		})
+		binary.Write(&buf, e.order, elf.Prog32{
+			Type: uint32(elf.PT_NOTE), Off: uint32(52 + 32 + e.gap), Filesz: uint32(noteSize),
+		})
+	} else {
+		binary.Write(&buf, e.order, elf.Header64{
+			Ident: ident, Type: uint16(e.typ), Machine: uint16(e.machine), Version: uint32(elf.EV_CURRENT),
+			Phoff: 64, Ehsize: 64, Phentsize: 56, Phnum: 1,
+		})
+		binary.Write(&buf, e.order, elf.Prog64{
+			Type: uint32(elf.PT_NOTE), Off: uint64(64 + 56 + e.gap), Filesz: noteSize,
+		})
+	}
+	buf.Write(make([]byte, e.gap))
+	buf.Write(notes)
+	buf.Write(e.tail)
+	return buf.Bytes()
+}
+
+// words encodes unsigned longs of the class.
+func words(class elf.Class, order binary.ByteOrder, values ...uint64) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if class == elf.ELFCLASS32 {
+			binary.Write(&buf, order, uint32(v))
+		} else {
+			binary.Write(&buf, order, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+// prstatus returns struct elf_prstatus with pr_cursig, pr_pid and the
+// registers, whose values are 0x1000 plus their index.
+func prstatus(class elf.Class, order binary.ByteOrder, cursig uint16, pid uint32, nregs int) []byte {
+	pidOff, regOff, wordSize := 32, 112, 8
+	if class == elf.ELFCLASS32 {
+		pidOff, regOff, wordSize = 24, 72, 4
+	}
+	desc := make([]byte, regOff+nregs*wordSize+4)
+	order.PutUint16(desc[12:], cursig)
+	order.PutUint32(desc[pidOff:], pid)
+	for i := 0; i < nregs; i++ {
+		copy(desc[regOff+i*wordSize:], words(class, order, uint64(0x1000+i)))
+	}
+	return desc
+}
+
+// prpsinfo returns struct elf_prpsinfo of the size, pr_fname and pr_psargs
+// are at its end.
+func prpsinfo(size int, fname, psargs string) []byte {
+	desc := make([]byte, size)
+	copy(desc[size-96:], fname)
+	copy(desc[size-80:], psargs)
+	return desc
+}
+
+// siginfo returns siginfo_t with si_signo, si_code and si_addr.
+func siginfo(class elf.Class, order binary.ByteOrder, signo, code int32, addr uint64) []byte {
+	addrOff := 16
+	if class == elf.ELFCLASS32 {
+		addrOff = 12
+	}
+	desc := make([]byte, 128)
+	order.PutUint32(desc, uint32(signo))
+	order.PutUint32(desc[8:], uint32(code))
+	copy(desc[addrOff:], words(class, order, addr))
+	return desc
+}
+
+// parse streams data to a parser in chunks of the size.
+func parse(data []byte, chunk int) (*Notes, error) {
+	p := NewParser()
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if written, err := p.Write(data[:n]); written != n || err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return p.Notes()
+}
+
+func TestParseX86_64(t *testing.T) {
+	le := binary.LittleEndian
+	core := testELF{
+		notes: []note{
+			{"CORE", ntPrstatus, prstatus(elf.ELFCLASS64, le, 6, 42, 27)},
+			// another thread, only the first is the crashing one.
+			{"CORE", ntPrstatus, prstatus(elf.ELFCLASS64, le, 0, 43, 27)},
+			{"CORE", ntPrpsinfo, prpsinfo(136, "crash", "./crash foo bar ")},
+			{"CORE", ntSiginfo, siginfo(elf.ELFCLASS64, le, 11, 1, 0xdead)},
+			{"CORE", ntAuxv, words(elf.ELFCLASS64, le, 6, 4096, 33, 0x7ffd000, 0, 0, 9, 9)},
+			// notes of other names are skipped.
+			{"LINUX", ntPrstatus, prstatus(elf.ELFCLASS64, le, 9, 99, 27)},
+		},
+		gap:  100,
+		tail: make([]byte, 4096),
+	}
+	data := core.bytes()
+	for _, chunk := range []int{1, 7, 64, len(data)} {
+		notes, err := parse(data, chunk)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", chunk, err)
+		}
+		if notes.Architecture != "x86_64" {
+			t.Errorf("chunk %d: Architecture = %q", chunk, notes.Architecture)
+		}
+		// NT_SIGINFO takes precedence over pr_cursig.
+		if notes.Signal != 11 || notes.SignalCode != 1 {
+			t.Errorf("chunk %d: signal = %d/%d, want 11/1", chunk, notes.Signal, notes.SignalCode)
+		}
+		if notes.FaultAddress == nil || *notes.FaultAddress != 0xdead {
+			t.Errorf("chunk %d: FaultAddress = %v, want 0xdead", chunk, notes.FaultAddress)
+		}
+		if notes.TID != 42 {
+			t.Errorf("chunk %d: TID = %d, want 42", chunk, notes.TID)
+		}
+		if notes.Fname != "crash" || notes.Psargs != "./crash foo bar" {
+			t.Errorf("chunk %d: Fname = %q, Psargs = %q", chunk, notes.Fname, notes.Psargs)
+		}
+		if len(notes.Registers) != 27 || notes.Registers[16] != (Register{"rip", 0x1000 + 16}) || notes.Registers[19] != (Register{"rsp", 0x1000 + 19}) {
+			t.Errorf("chunk %d: Registers = %v", chunk, notes.Registers)
+		}
+		// entries after AT_NULL are ignored.
+		if len(notes.Auxv) != 2 || notes.Auxv[6] != 4096 || notes.Auxv[33] != 0x7ffd000 {
+			t.Errorf("chunk %d: Auxv = %v", chunk, notes.Auxv)
+		}
+	}
+}
+
+func TestParseI386(t *testing.T) {
+	le := binary.LittleEndian
+	core := testELF{
+		class:   elf.ELFCLASS32,
+		machine: elf.EM_386,
+		notes: []note{
+			// SIGABRT has no NT_SIGINFO here, pr_cursig is used.
+			{"CORE", ntPrstatus, prstatus(elf.ELFCLASS32, le, 6, 7, 17)},
+			{"CORE", ntPrpsinfo, prpsinfo(124, "abort", "abort -x")},
+		},
+	}
+	notes, err := parse(core.bytes(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notes.Architecture != "i386" || notes.Signal != 6 || notes.TID != 7 {
+		t.Errorf("notes = %+v", notes)
+	}
+	if notes.FaultAddress != nil {
+		t.Errorf("FaultAddress = %#x, want none", *notes.FaultAddress)
+	}
+	if len(notes.Registers) != 17 || notes.Registers[12] != (Register{"eip", 0x1000 + 12}) {
+		t.Errorf("Registers = %v", notes.Registers)
+	}
+	if notes.Fname != "abort" || notes.Psargs != "abort -x" {
+		t.Errorf("Fname = %q, Psargs = %q", notes.Fname, notes.Psargs)
+	}
+}
+
+func TestParseSiginfo(t *testing.T) {
+	be := binary.BigEndian
+	for _, tc := range []struct {
+		name      string
+		signo     int32
+		code      int32
+		wantFault bool
+	}{
+		{"SIGSEGV", 11, 2, true},
+		{"SIGBUS", 7, 1, true},
+		// sent by kill(2), si_addr is not an address.
+		{"SIGSEGV by kill", 11, 0, false},
+		{"SIGABRT", 6, -6, false},
+	} {
+		core := testELF{
+			class:   elf.ELFCLASS64,
+			order:   be,
+			machine: elf.EM_S390,
+			notes: []note{
+				{"CORE", ntSiginfo, siginfo(elf.ELFCLASS64, be, tc.signo, tc.code, 0x1234)},
+			},
+		}
+		notes, err := parse(core.bytes(), 3)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if notes.Architecture != "s390x" || notes.Signal != int(tc.signo) || notes.SignalCode != int(tc.code) {
+			t.Errorf("%s: notes = %+v", tc.name, notes)
+		}
+		if got := notes.FaultAddress != nil && *notes.FaultAddress == 0x1234; got != tc.wantFault {
+			t.Errorf("%s: FaultAddress = %v, want fault %v", tc.name, notes.FaultAddress, tc.wantFault)
+		}
+		// registers of unknown architectures aren't read.
+		if len(notes.Registers) != 0 {
+			t.Errorf("%s: Registers = %v", tc.name, notes.Registers)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	le := binary.LittleEndian
+	valid := testELF{notes: []note{{"CORE", ntPrstatus, prstatus(elf.ELFCLASS64, le, 11, 1, 27)}}}.bytes()
+	notELF := append([]byte("#!/bin/sh"), make([]byte, 100)...)
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not ELF", notELF, "not an ELF file"},
+		{"not a core", testELF{typ: elf.ET_EXEC}.bytes(), "not an ELF core file"},
+		{"truncated header", valid[:40], "unexpected end"},
+		{"truncated notes", valid[:len(valid)-10], "unexpected end"},
+		{"oversized notes", testELF{noteSize: maxNotesSize + 1}.bytes(), "invalid PT_NOTE"},
+		{"note exceeds PT_NOTE", testELF{
+			notes:    []note{{"CORE", ntPrstatus, prstatus(elf.ELFCLASS64, le, 11, 1, 27)}},
+			noteSize: 100,
+			tail:     make([]byte, 1000),
+		}.bytes(), "invalid note"},
+	} {
+		_, err := parse(tc.data, 16)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	for signal, want := range map[int]string{11: "SIGSEGV", 6: "SIGABRT", 31: "SIGSYS", 34: "SIGRTMIN+0", 40: "SIGRTMIN+6", 0: "SIG0", 65: "SIG65"} {
+		if got := SignalName(signal); got != want {
+			t.Errorf("SignalName(%d) = %q, want %q", signal, got, want)
+		}
+	}
+}
@@ -27,6 +27,10 @@ import (
 
 type Client interface {
 	GetPod(namespace, name string) (ret *v1.Pod, err error)
+	GetNamespace(name string) (ret *v1.Namespace, err error)
+	GetEvent(namespace, name string) (ret *v1.Event, err error)
+	CreateEvent(event *v1.Event) (ret *v1.Event, err error)
+	UpdateEvent(event *v1.Event) (ret *v1.Event, err error)
 }
 
 type kubeClient struct {
@@ -34,26 +38,46 @@ type kubeClient struct {
 }
 
 func NewClientOrDie(kubeConfig string) Client {
-	c := &kubeClient{}
-	c.clientset = newClientsetOrDie(kubeConfig)
-	return c
+	return NewClient(NewClientsetOrDie(kubeConfig))
+}
+
+// NewClient returns the client backed by the clientset.
+func NewClient(clientset *kubernetes.Clientset) Client {
+	return &kubeClient{clientset: clientset}
 }
 
 func (c *kubeClient) GetPod(namespace, name string) (ret *v1.Pod, err error) {
 	return c.clientset.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
 }
 
-func newClientsetOrDie(kubeConfig string) *kubernetes.Clientset {
+func (c *kubeClient) GetNamespace(name string) (ret *v1.Namespace, err error) {
+	return c.clientset.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+}
+
+func (c *kubeClient) GetEvent(namespace, name string) (ret *v1.Event, err error) {
+	return c.clientset.CoreV1().Events(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *kubeClient) CreateEvent(event *v1.Event) (ret *v1.Event, err error) {
+	return c.clientset.CoreV1().Events(event.ObjectMeta.Namespace).Create(event)
+}
+
+func (c *kubeClient) UpdateEvent(event *v1.Event) (ret *v1.Event, err error) {
+	return c.clientset.CoreV1().Events(event.ObjectMeta.Namespace).Update(event)
+}
+
+// NewClientsetOrDie returns the clientset of kubernetes core types.
+func NewClientsetOrDie(kubeConfig string) *kubernetes.Clientset {
 	// use the current context in kubeconfig
 	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
 
 	// create the clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		glog.Errorf(err.Error())
+		glog.Error(err)
 	}
 	return clientset
 
@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libcri
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// connection and request timeout of the CRI runtime service.
+const timeout = 10 * time.Second
+
+const unixProtocol = "unix"
+
+type Client interface {
+	ListContainers(filter *runtimeapi.ContainerFilter) ([]*runtimeapi.Container, error)
+	ContainerStatus(containerID string, verbose bool) (*runtimeapi.ContainerStatusResponse, error)
+}
+
+type criClient struct {
+	cli runtimeapi.RuntimeServiceClient
+}
+
+func (c criClient) ListContainers(filter *runtimeapi.ContainerFilter) ([]*runtimeapi.Container, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := c.cli.ListContainers(ctx, &runtimeapi.ListContainersRequest{Filter: filter})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Containers, nil
+}
+
+func (c criClient) ContainerStatus(containerID string, verbose bool) (*runtimeapi.ContainerStatusResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.cli.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{
+		ContainerId: containerID,
+		Verbose:     verbose,
+	})
+}
+
+// NewClientOrDie connects to the CRI runtime service listening on endpoint,
+// e.g. unix:///run/containerd/containerd.sock or unix:///var/run/crio/crio.sock.
+func NewClientOrDie(endpoint string) Client {
+	addr, err := parseEndpoint(endpoint)
+	if err != nil {
+		panic(err)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithTimeout(timeout), grpc.WithDialer(dial))
+	if err != nil {
+		panic(err)
+	}
+	return &criClient{cli: runtimeapi.NewRuntimeServiceClient(conn)}
+}
+
+func parseEndpoint(endpoint string) (string, error) {
+	// unix socket path without scheme is accepted as kubelet does.
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, nil
+	}
+	if !strings.HasPrefix(endpoint, unixProtocol+"://") {
+		return "", fmt.Errorf("only unix socket is supported, got endpoint %q", endpoint)
+	}
+	return strings.TrimPrefix(endpoint, unixProtocol+"://"), nil
+}
+
+func dial(addr string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(unixProtocol, addr, timeout)
+}
@@ -26,6 +26,7 @@ import (
 type Client interface {
 	ContainerList(options types.ContainerListOptions) ([]types.Container, error)
 	ContainerTop(containerID string) (container.ContainerTopOKBody, error)
+	ContainerInspect(containerID string) (types.ContainerJSON, error)
 }
 
 type dockerClient struct {
@@ -42,9 +43,22 @@ func (c dockerClient) ContainerTop(containerID string) (container.ContainerTopOK
 	return c.cli.ContainerTop(ctx, containerID, nil)
 }
 
-func NewClientOrDie() Client {
+// ContainerInspect returns the details of the container, e.g. its labels.
+func (c dockerClient) ContainerInspect(containerID string) (types.ContainerJSON, error) {
+	ctx := context.Background()
+	return c.cli.ContainerInspect(ctx, containerID)
+}
+
+const defaultHost = "unix:///var/run/docker.sock"
+
+// NewClientOrDie connects to dockerd listening on host, the default host
+// is used if it's empty.
+func NewClientOrDie(host string) Client {
 	//of course we only support dockerd running in localhost.
-	cli, err := client.NewClient("unix:///var/run/docker.sock", "", nil, nil)
+	if host == "" {
+		host = defaultHost
+	}
+	cli, err := client.NewClient(host, "", nil, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,130 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+// Result is the result of evaluating the coredump policies of a namespace.
+type Result struct {
+	// Allowed is false if there are policies but none of them matches.
+	Allowed bool
+	Message string
+	// MaxSize is the smallest max size of matched policies, nil means unlimited.
+	MaxSize *resource.Quantity
+	// MaxSizePolicy is the name of the policy where MaxSize comes from.
+	MaxSizePolicy string
+	// Retention is the shortest retention of matched policies, nil means forever.
+	Retention *time.Duration
+}
+
+// Evaluate evaluates the policies for a coredump of the executable, which
+// happens in the container of a pod with the labels.
+func Evaluate(policies []coredump.CoredumpPolicy, podLabels map[string]string, containerName, filename string) (*Result, error) {
+	if len(policies) == 0 {
+		return &Result{Allowed: true}, nil
+	}
+	result := &Result{}
+	for i := range policies {
+		p := &policies[i]
+		ok, err := matches(p, podLabels, containerName, filename)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coredump policy %s: %v", p.ObjectMeta.Name, err)
+		}
+		if !ok {
+			continue
+		}
+		result.Allowed = true
+		if p.Spec.MaxSize != nil && (result.MaxSize == nil || p.Spec.MaxSize.Cmp(*result.MaxSize) < 0) {
+			maxSize := p.Spec.MaxSize.DeepCopy()
+			result.MaxSize = &maxSize
+			result.MaxSizePolicy = p.ObjectMeta.Name
+		}
+		if p.Spec.Retention != nil && (result.Retention == nil || p.Spec.Retention.Duration < *result.Retention) {
+			retention := p.Spec.Retention.Duration
+			result.Retention = &retention
+		}
+	}
+	if !result.Allowed {
+		result.Message = fmt.Sprintf("executable %s in container %s doesn't match any coredump policy", filename, containerName)
+	}
+	return result, nil
+}
+
+// AllowSize checks the size against the max size of the result, and returns
+// the reason if it's not allowed.
+func (r *Result) AllowSize(size *resource.Quantity) (bool, string) {
+	if r.MaxSize == nil || size.Cmp(*r.MaxSize) <= 0 {
+		return true, ""
+	}
+	return false, r.TooLargeMessage()
+}
+
+// TooLargeMessage returns the reason why a coredump exceeding MaxSize is not allowed.
+func (r *Result) TooLargeMessage() string {
+	return fmt.Sprintf("coredump exceeds max size %s of coredump policy %s", r.MaxSize.String(), r.MaxSizePolicy)
+}
+
+func matches(p *coredump.CoredumpPolicy, podLabels map[string]string, containerName, filename string) (bool, error) {
+	if len(p.Spec.Executables) > 0 {
+		matched := false
+		for _, pattern := range p.Spec.Executables {
+			ok, err := path.Match(pattern, filename)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if len(p.Spec.Containers) > 0 {
+		matched := false
+		for _, name := range p.Spec.Containers {
+			if name == containerName {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if p.Spec.PodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(p.Spec.PodSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(podLabels)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+)
+
+func newPolicy(name string, spec coredump.CoredumpPolicySpec) coredump.CoredumpPolicy {
+	return coredump.CoredumpPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func duration(d time.Duration) *metav1.Duration {
+	return &metav1.Duration{Duration: d}
+}
+
+func TestEvaluate(t *testing.T) {
+	podLabels := map[string]string{"app": "web", "tier": "frontend"}
+	webPods := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	dbPods := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}
+
+	for _, tc := range []struct {
+		name     string
+		policies []coredump.CoredumpPolicy
+		// want is the result for a coredump of nginx in container web of
+		// podLabels.
+		wantAllowed       bool
+		wantMaxSize       string
+		wantMaxSizePolicy string
+		wantRetention     time.Duration
+	}{
+		{
+			name:        "no policy",
+			wantAllowed: true,
+		},
+		{
+			name: "match all",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("all", coredump.CoredumpPolicySpec{}),
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "executable pattern",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("ngx", coredump.CoredumpPolicySpec{Executables: []string{"java", "ngin?"}}),
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "executable not matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("java", coredump.CoredumpPolicySpec{Executables: []string{"java*"}}),
+			},
+		},
+		{
+			name: "container not matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("sidecar", coredump.CoredumpPolicySpec{Containers: []string{"sidecar"}}),
+			},
+		},
+		{
+			name: "pod selector not matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("db", coredump.CoredumpPolicySpec{PodSelector: dbPods}),
+			},
+		},
+		{
+			name: "all conditions matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("web", coredump.CoredumpPolicySpec{
+					Executables: []string{"nginx"},
+					Containers:  []string{"sidecar", "web"},
+					PodSelector: webPods,
+				}),
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "one condition not matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("web", coredump.CoredumpPolicySpec{
+					Executables: []string{"nginx"},
+					Containers:  []string{"sidecar"},
+					PodSelector: webPods,
+				}),
+			},
+		},
+		{
+			// limits of a policy not matched don't apply.
+			name: "denied without limits",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("db", coredump.CoredumpPolicySpec{PodSelector: dbPods, MaxSize: quantity("1Mi"), Retention: duration(time.Hour)}),
+			},
+		},
+		{
+			name: "any policy matched",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("db", coredump.CoredumpPolicySpec{PodSelector: dbPods, MaxSize: quantity("1Mi"), Retention: duration(time.Hour)}),
+				newPolicy("web", coredump.CoredumpPolicySpec{PodSelector: webPods, MaxSize: quantity("1Gi"), Retention: duration(24 * time.Hour)}),
+			},
+			wantAllowed:       true,
+			wantMaxSize:       "1Gi",
+			wantMaxSizePolicy: "web",
+			wantRetention:     24 * time.Hour,
+		},
+		{
+			name: "smallest max size and shortest retention",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("small", coredump.CoredumpPolicySpec{MaxSize: quantity("100Mi"), Retention: duration(48 * time.Hour)}),
+				newPolicy("short", coredump.CoredumpPolicySpec{MaxSize: quantity("1Gi"), Retention: duration(time.Hour)}),
+				newPolicy("unlimited", coredump.CoredumpPolicySpec{}),
+			},
+			wantAllowed:       true,
+			wantMaxSize:       "100Mi",
+			wantMaxSizePolicy: "small",
+			wantRetention:     time.Hour,
+		},
+		{
+			name: "smallest max size and shortest retention in reverse order",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("unlimited", coredump.CoredumpPolicySpec{}),
+				newPolicy("short", coredump.CoredumpPolicySpec{MaxSize: quantity("1Gi"), Retention: duration(time.Hour)}),
+				newPolicy("small", coredump.CoredumpPolicySpec{MaxSize: quantity("100Mi"), Retention: duration(48 * time.Hour)}),
+			},
+			wantAllowed:       true,
+			wantMaxSize:       "100Mi",
+			wantMaxSizePolicy: "small",
+			wantRetention:     time.Hour,
+		},
+		{
+			name: "first of equal max sizes",
+			policies: []coredump.CoredumpPolicy{
+				newPolicy("first", coredump.CoredumpPolicySpec{MaxSize: quantity("1024Mi")}),
+				newPolicy("second", coredump.CoredumpPolicySpec{MaxSize: quantity("1Gi")}),
+			},
+			wantAllowed:       true,
+			wantMaxSize:       "1Gi",
+			wantMaxSizePolicy: "first",
+		},
+	} {
+		result, err := Evaluate(tc.policies, podLabels, "web", "nginx")
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if result.Allowed != tc.wantAllowed {
+			t.Errorf("%s: allowed = %v, want %v", tc.name, result.Allowed, tc.wantAllowed)
+		}
+		if !result.Allowed && !strings.Contains(result.Message, "doesn't match any coredump policy") {
+			t.Errorf("%s: message of denied result = %q", tc.name, result.Message)
+		}
+		if result.Allowed && result.Message != "" {
+			t.Errorf("%s: message of allowed result = %q", tc.name, result.Message)
+		}
+		switch {
+		case tc.wantMaxSize == "" && result.MaxSize != nil:
+			t.Errorf("%s: max size = %s, want unlimited", tc.name, result.MaxSize.String())
+		case tc.wantMaxSize != "" && (result.MaxSize == nil || result.MaxSize.Cmp(resource.MustParse(tc.wantMaxSize)) != 0):
+			t.Errorf("%s: max size = %v, want %s", tc.name, result.MaxSize, tc.wantMaxSize)
+		}
+		if result.MaxSizePolicy != tc.wantMaxSizePolicy {
+			t.Errorf("%s: max size policy = %q, want %q", tc.name, result.MaxSizePolicy, tc.wantMaxSizePolicy)
+		}
+		switch {
+		case tc.wantRetention == 0 && result.Retention != nil:
+			t.Errorf("%s: retention = %s, want forever", tc.name, *result.Retention)
+		case tc.wantRetention != 0 && (result.Retention == nil || *result.Retention != tc.wantRetention):
+			t.Errorf("%s: retention = %v, want %s", tc.name, result.Retention, tc.wantRetention)
+		}
+	}
+}
+
+func TestEvaluateInvalidPolicy(t *testing.T) {
+	for _, p := range []coredump.CoredumpPolicy{
+		newPolicy("pattern", coredump.CoredumpPolicySpec{Executables: []string{"[nginx"}}),
+		newPolicy("selector", coredump.CoredumpPolicySpec{PodSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bad"}},
+		}}),
+	} {
+		if _, err := Evaluate([]coredump.CoredumpPolicy{p}, nil, "web", "nginx"); err == nil || !strings.Contains(err.Error(), p.ObjectMeta.Name) {
+			t.Errorf("policy %s: err = %v, want error of the invalid policy", p.ObjectMeta.Name, err)
+		}
+	}
+}
+
+func TestAllowSize(t *testing.T) {
+	unlimited := &Result{Allowed: true}
+	if ok, _ := unlimited.AllowSize(quantity("1Ti")); !ok {
+		t.Error("coredump is not allowed without max size")
+	}
+	limited := &Result{Allowed: true, MaxSize: quantity("1Mi"), MaxSizePolicy: "small"}
+	if ok, _ := limited.AllowSize(quantity("1Mi")); !ok {
+		t.Error("coredump of exactly the max size is not allowed")
+	}
+	ok, reason := limited.AllowSize(quantity("1048577"))
+	if ok || !strings.Contains(reason, "small") {
+		t.Errorf("AllowSize of 1 byte over the max size = %v, %q, want not allowed by policy small", ok, reason)
+	}
+}
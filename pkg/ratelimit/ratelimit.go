@@ -0,0 +1,261 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ratelimit limits the rate of coredumps handled in a node. Every
+// coredump is handled by a new coredump-detector process, so the token
+// buckets are kept in a state file shared by all processes, which is locked
+// while a process takes a token.
+package ratelimit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// nodeKey is the key of the bucket shared by all coredumps of the node.
+const nodeKey = ""
+
+// staleAfter is how long a bucket not used is kept in the state file.
+const staleAfter = 24 * time.Hour
+
+// quietPeriod is how long no coredump of a key is dropped before its
+// suppression window is closed and reported by Flush.
+const quietPeriod = time.Minute
+
+// Limit is the rate of a token bucket.
+type Limit struct {
+	// PerMinute is the number of tokens added per minute, 0 means unlimited.
+	PerMinute float64
+	// Burst is the max number of tokens in the bucket.
+	Burst int
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	Tokens float64   `json:"tokens"`
+	Last   time.Time `json:"last"`
+	// Suppressed is the number of coredumps dropped since the last one
+	// handled, or since they're reported by Flush.
+	Suppressed int64 `json:"suppressed,omitempty"`
+	// FirstSuppressed is the time of the first coredump dropped.
+	FirstSuppressed time.Time `json:"firstSuppressed,omitempty"`
+	// LastSuppressed is the time of the last coredump dropped.
+	LastSuppressed time.Time `json:"lastSuppressed,omitempty"`
+}
+
+// reset forgets the coredumps dropped once they're reported.
+func (b *bucket) reset() {
+	b.Suppressed = 0
+	b.FirstSuppressed = time.Time{}
+	b.LastSuppressed = time.Time{}
+}
+
+// refill adds the tokens since the last refill.
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if elapsed := now.Sub(b.Last); elapsed > 0 {
+		b.Tokens += elapsed.Minutes() * limit.PerMinute
+	}
+	if b.Tokens > float64(limit.Burst) {
+		b.Tokens = float64(limit.Burst)
+	}
+	b.Last = now
+}
+
+// Decision is the result of taking a token.
+type Decision struct {
+	// Allowed is true if the coredump should be handled.
+	Allowed bool
+	// Suppressed is the number of coredumps of the same key dropped before
+	// this allowed one, they're reported with it.
+	Suppressed int64
+	// FirstSuppressed is the time of the first coredump dropped.
+	FirstSuppressed time.Time
+}
+
+// Summary is the coredumps of a key dropped in a suppression window, which is
+// closed without any coredump of the key handled.
+type Summary struct {
+	Key             string
+	Suppressed      int64
+	FirstSuppressed time.Time
+	LastSuppressed  time.Time
+}
+
+// Limiter takes tokens from the bucket of a key, e.g. a container, and the
+// bucket of the node.
+type Limiter struct {
+	// StateFile is the file where buckets are kept.
+	StateFile string
+	Key       Limit
+	Node      Limit
+}
+
+// Take takes a token from the bucket of key and the bucket of the node. The
+// coredump is dropped if either bucket is empty, and it's counted as
+// suppressed of key.
+func (l *Limiter) Take(key string, now time.Time) (*Decision, error) {
+	if l.Key.PerMinute <= 0 && l.Node.PerMinute <= 0 {
+		return &Decision{Allowed: true}, nil
+	}
+	var decision *Decision
+	err := l.update(func(buckets map[string]*bucket) {
+		decision = l.take(buckets, key, now)
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
+
+// Flush returns the suppression windows closed, i.e. no coredump of the key
+// is dropped in the last quietPeriod, and forgets them. The coredumps dropped
+// are reported by the caller, so they're reported even if no coredump of the
+// key is handled later, e.g. the crash loop stops or the pod is deleted.
+func (l *Limiter) Flush(now time.Time) ([]Summary, error) {
+	if _, err := os.Stat(l.StateFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var summaries []Summary
+	err := l.update(func(buckets map[string]*bucket) {
+		summaries = flush(buckets, now)
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// update runs fn with the buckets of the state file locked, and writes them
+// back without stale buckets.
+func (l *Limiter) update(fn func(buckets map[string]*bucket), now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(l.StateFile), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.StateFile, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// the lock is released when the file is closed.
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+
+	buckets, err := readBuckets(f)
+	if err != nil {
+		return err
+	}
+	fn(buckets)
+	prune(buckets, now)
+	return writeBuckets(f, buckets)
+}
+
+func (l *Limiter) take(buckets map[string]*bucket, key string, now time.Time) *Decision {
+	keyBucket := getBucket(buckets, key, l.Key, now)
+	nodeBucket := getBucket(buckets, nodeKey, l.Node, now)
+	keyAllowed := l.Key.PerMinute <= 0 || keyBucket.Tokens >= 1
+	nodeAllowed := l.Node.PerMinute <= 0 || nodeBucket.Tokens >= 1
+	if !keyAllowed || !nodeAllowed {
+		if keyBucket.Suppressed == 0 {
+			keyBucket.FirstSuppressed = now
+		}
+		keyBucket.Suppressed++
+		keyBucket.LastSuppressed = now
+		return &Decision{}
+	}
+	if l.Key.PerMinute > 0 {
+		keyBucket.Tokens--
+	}
+	if l.Node.PerMinute > 0 {
+		nodeBucket.Tokens--
+	}
+	decision := &Decision{
+		Allowed:         true,
+		Suppressed:      keyBucket.Suppressed,
+		FirstSuppressed: keyBucket.FirstSuppressed,
+	}
+	keyBucket.reset()
+	return decision
+}
+
+// flush returns the suppression windows closed, and resets their buckets.
+func flush(buckets map[string]*bucket, now time.Time) []Summary {
+	var summaries []Summary
+	for key, b := range buckets {
+		if b.Suppressed == 0 || now.Sub(b.LastSuppressed) < quietPeriod {
+			continue
+		}
+		summaries = append(summaries, Summary{
+			Key:             key,
+			Suppressed:      b.Suppressed,
+			FirstSuppressed: b.FirstSuppressed,
+			LastSuppressed:  b.LastSuppressed,
+		})
+		b.reset()
+	}
+	return summaries
+}
+
+// prune removes buckets not used for staleAfter. Coredumps dropped are
+// reported by Flush long before, unless nothing flushes the state file.
+func prune(buckets map[string]*bucket, now time.Time) {
+	for key, b := range buckets {
+		if now.Sub(b.Last) > staleAfter {
+			delete(buckets, key)
+		}
+	}
+}
+
+// getBucket returns the refilled bucket of key, a new bucket is full.
+func getBucket(buckets map[string]*bucket, key string, limit Limit, now time.Time) *bucket {
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{Tokens: float64(limit.Burst), Last: now}
+		buckets[key] = b
+	}
+	b.refill(limit, now)
+	return b
+}
+
+// readBuckets reads the buckets from the state file, a corrupted file is
+// reset.
+func readBuckets(f *os.File) (map[string]*bucket, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	buckets := map[string]*bucket{}
+	if len(data) > 0 && json.Unmarshal(data, &buckets) != nil {
+		buckets = map[string]*bucket{}
+	}
+	return buckets, nil
+}
+
+func writeBuckets(f *os.File, buckets map[string]*bucket) error {
+	data, err := json.Marshal(buckets)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ratelimit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2017, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// step is a coredump of key taken at start+at.
+type step struct {
+	key string
+	at  time.Duration
+	// want is the expected decision.
+	allowed    bool
+	suppressed int64
+}
+
+func TestTake(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		limiter Limiter
+		steps   []step
+	}{
+		{
+			name:    "burst",
+			limiter: Limiter{Key: Limit{PerMinute: 1, Burst: 3}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", allowed: false},
+				// other keys have their own buckets.
+				{key: "b", allowed: true},
+			},
+		},
+		{
+			name:    "refill over time",
+			limiter: Limiter{Key: Limit{PerMinute: 2, Burst: 1}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", at: 10 * time.Second, allowed: false},
+				{key: "a", at: 29 * time.Second, allowed: false},
+				// half a minute refills a token.
+				{key: "a", at: 31 * time.Second, allowed: true, suppressed: 2},
+				{key: "a", at: 32 * time.Second, allowed: false},
+			},
+		},
+		{
+			name:    "refill up to burst",
+			limiter: Limiter{Key: Limit{PerMinute: 60, Burst: 2}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "a", at: time.Hour, allowed: true},
+				{key: "a", at: time.Hour, allowed: true},
+				{key: "a", at: time.Hour, allowed: false},
+			},
+		},
+		{
+			name:    "suppressed reset after reported",
+			limiter: Limiter{Key: Limit{PerMinute: 1, Burst: 1}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: false},
+				{key: "a", allowed: false},
+				{key: "a", allowed: false},
+				{key: "a", at: time.Minute, allowed: true, suppressed: 3},
+				{key: "a", at: time.Minute, allowed: false},
+				{key: "a", at: 2 * time.Minute, allowed: true, suppressed: 1},
+				{key: "a", at: 3 * time.Minute, allowed: true},
+			},
+		},
+		{
+			name:    "node limit",
+			limiter: Limiter{Key: Limit{PerMinute: 10, Burst: 10}, Node: Limit{PerMinute: 1, Burst: 2}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "b", allowed: true},
+				// dropped by the node, counted as suppressed of the key.
+				{key: "c", allowed: false},
+				{key: "c", at: time.Minute, allowed: true, suppressed: 1},
+				{key: "a", at: time.Minute, allowed: false},
+			},
+		},
+		{
+			name:    "unlimited key",
+			limiter: Limiter{Node: Limit{PerMinute: 1, Burst: 2}},
+			steps: []step{
+				{key: "a", allowed: true},
+				{key: "a", allowed: true},
+				{key: "b", allowed: false},
+			},
+		},
+	} {
+		buckets := map[string]*bucket{}
+		for i, s := range tc.steps {
+			d := tc.limiter.take(buckets, s.key, start.Add(s.at))
+			if d.Allowed != s.allowed || d.Suppressed != s.suppressed {
+				t.Errorf("%s: step %d: got allowed %v, suppressed %d, want %v, %d",
+					tc.name, i, d.Allowed, d.Suppressed, s.allowed, s.suppressed)
+			}
+			if d.Suppressed > 0 && d.FirstSuppressed.IsZero() {
+				t.Errorf("%s: step %d: FirstSuppressed is not set", tc.name, i)
+			}
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	limiter := Limiter{Key: Limit{PerMinute: 1, Burst: 1}}
+	buckets := map[string]*bucket{}
+	limiter.take(buckets, "a", start)
+	limiter.take(buckets, "a", start.Add(time.Second))
+	limiter.take(buckets, "a", start.Add(2*time.Second))
+	limiter.take(buckets, "b", start)
+
+	for _, tc := range []struct {
+		at   time.Duration
+		want []Summary
+	}{
+		{at: 2*time.Second + quietPeriod - time.Second},
+		{at: 2*time.Second + quietPeriod, want: []Summary{{
+			Key:             "a",
+			Suppressed:      2,
+			FirstSuppressed: start.Add(time.Second),
+			LastSuppressed:  start.Add(2 * time.Second),
+		}}},
+		// the summary is reported once.
+		{at: time.Hour},
+	} {
+		got := flush(buckets, start.Add(tc.at))
+		if len(got) != len(tc.want) {
+			t.Errorf("flush at %s: got %+v, want %+v", tc.at, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("flush at %s: got %+v, want %+v", tc.at, got[i], tc.want[i])
+			}
+		}
+	}
+	if d := limiter.take(buckets, "a", start.Add(time.Hour)); !d.Allowed || d.Suppressed != 0 {
+		t.Errorf("coredumps reported by flush are reported again: %+v", d)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	limiter := Limiter{Key: Limit{PerMinute: 1, Burst: 1}}
+	buckets := map[string]*bucket{}
+	limiter.take(buckets, "old", start)
+	limiter.take(buckets, "old", start)
+	limiter.take(buckets, "new", start.Add(time.Hour))
+
+	prune(buckets, start.Add(staleAfter))
+	if _, ok := buckets["old"]; !ok {
+		t.Error("bucket used staleAfter ago is pruned")
+	}
+	prune(buckets, start.Add(staleAfter+time.Second))
+	if _, ok := buckets["old"]; ok {
+		t.Error("stale bucket with suppressed coredumps is not pruned")
+	}
+	for _, key := range []string{"new", nodeKey} {
+		if _, ok := buckets[key]; !ok {
+			t.Errorf("bucket %q used recently is pruned", key)
+		}
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state", "ratelimit")
+
+	flushLimiter := &Limiter{StateFile: stateFile}
+	if summaries, err := flushLimiter.Flush(start); err != nil || summaries != nil {
+		t.Errorf("Flush without state file = %v, %v", summaries, err)
+	}
+	if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+		t.Errorf("state file is created by Flush: %v", err)
+	}
+
+	// every coredump is taken by a new process, which only shares the file.
+	take := func(key string, at time.Duration) *Decision {
+		limiter := &Limiter{StateFile: stateFile, Key: Limit{PerMinute: 1, Burst: 1}}
+		d, err := limiter.Take(key, start.Add(at))
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		return d
+	}
+	if d := take("a", 0); !d.Allowed {
+		t.Error("the first coredump is dropped")
+	}
+	if d := take("a", time.Second); d.Allowed {
+		t.Error("the second coredump exceeds the burst, but it's not dropped")
+	}
+	if d := take("a", time.Minute); !d.Allowed || d.Suppressed != 1 || !d.FirstSuppressed.Equal(start.Add(time.Second)) {
+		t.Errorf("Take after refill = %+v, want allowed with 1 suppressed", d)
+	}
+
+	take("b", 2*time.Minute)
+	take("b", 2*time.Minute)
+	summaries, err := flushLimiter.Flush(start.Add(2*time.Minute + quietPeriod))
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].Key != "b" || summaries[0].Suppressed != 1 {
+		t.Errorf("Flush = %+v, want 1 coredump of b", summaries)
+	}
+
+	// a corrupted file is reset.
+	if err := ioutil.WriteFile(stateFile, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if d := take("b", 3*time.Minute); !d.Allowed || d.Suppressed != 0 {
+		t.Errorf("Take with corrupted state file = %+v, want allowed", d)
+	}
+}
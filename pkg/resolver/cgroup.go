@@ -0,0 +1,142 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+var (
+	// pod cgroup created by cgroupfs driver, e.g. "pod2c3a1f8e-9d5b-11e7-a5b4-0800279fb07d".
+	// uid of static pods is a hash without "-".
+	cgroupfsPodRegexp = regexp.MustCompile(`^pod([0-9a-f-]{32,36})$`)
+	// pod cgroup created by systemd driver, e.g. "kubepods-burstable-pod2c3a1f8e_9d5b_11e7_a5b4_0800279fb07d.slice".
+	systemdPodRegexp = regexp.MustCompile(`^kubepods(?:-[a-z]+)?-pod([0-9a-f_]{32,36})\.slice$`)
+	// container cgroup, the id is prefixed by runtime name when it's created by
+	// systemd driver or cri-o, e.g. "docker-<id>.scope", "cri-containerd-<id>.scope",
+	// "crio-<id>.scope", "crio-<id>" and "<id>".
+	containerRegexp = regexp.MustCompile(`^(?:(?:docker|cri-containerd|crio)-)?([0-9a-f]{64})(?:\.scope)?$`)
+)
+
+type cgroupResolver struct {
+	runtime RuntimeResolver
+}
+
+// NewCgroupResolver returns a resolver which gets the pod uid and container
+// id from /proc/<pid>/cgroup, and then looks up the container by id from the
+// runtime. It falls back to the runtime resolver if the cgroup can't be parsed.
+func NewCgroupResolver(runtime RuntimeResolver) Resolver {
+	return &cgroupResolver{runtime: runtime}
+}
+
+func (r *cgroupResolver) Resolve(hostPid string) (*Container, error) {
+	podUID, containerID, err := parseCgroupFile("/proc/" + hostPid + "/cgroup")
+	if err != nil {
+		glog.Infof("failed to parse cgroup of process %s, fall back to runtime api: %v", hostPid, err)
+		return r.runtime.Resolve(hostPid)
+	}
+	if podUID == "" {
+		// not in a kubernetes pod
+		return nil, nil
+	}
+	container, err := r.runtime.ResolveID(containerID)
+	if err != nil {
+		glog.Infof("failed to look up container %s, fall back to runtime api: %v", containerID, err)
+		return r.runtime.Resolve(hostPid)
+	}
+	if container == nil || container.Uid != podUID {
+		glog.Infof("container %s is not in pod %s, fall back to runtime api", containerID, podUID)
+		return r.runtime.Resolve(hostPid)
+	}
+	return container, nil
+}
+
+// ContainerID returns the id of the kubernetes container where the process
+// runs from /proc/<pid>/cgroup, without calling the container runtime. It
+// returns "" if the process is not in a kubernetes pod.
+func ContainerID(hostPid string) (string, error) {
+	_, containerID, err := parseCgroupFile("/proc/" + hostPid + "/cgroup")
+	return containerID, err
+}
+
+func parseCgroupFile(filename string) (string, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	return parseCgroup(f)
+}
+
+// parseCgroup parses the content of /proc/<pid>/cgroup, and returns the pod
+// uid and container id. Both cgroup v1 lines "<id>:<controllers>:<path>" and
+// cgroup v2 line "0::<path>" are supported. An empty pod uid is returned if
+// the process is not in a kubernetes pod.
+func parseCgroup(r io.Reader) (string, string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			return "", "", fmt.Errorf("invalid cgroup line %q", scanner.Text())
+		}
+		podUID, containerID, err := parseCgroupPath(parts[2])
+		if err != nil {
+			return "", "", err
+		}
+		if podUID != "" {
+			return podUID, containerID, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+	return "", "", nil
+}
+
+// parseCgroupPath finds the pod cgroup in the path, the container cgroup is
+// the next one. e.g.
+// cgroupfs: /kubepods/burstable/pod<uid>/<id>
+// systemd:  /kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice/docker-<id>.scope
+func parseCgroupPath(cgroupPath string) (string, string, error) {
+	segments := strings.Split(cgroupPath, "/")
+	for i, segment := range segments {
+		podUID := ""
+		if m := cgroupfsPodRegexp.FindStringSubmatch(segment); m != nil {
+			podUID = m[1]
+		} else if m := systemdPodRegexp.FindStringSubmatch(segment); m != nil {
+			podUID = strings.Replace(m[1], "_", "-", -1)
+		} else {
+			continue
+		}
+		if i+1 >= len(segments) {
+			return "", "", fmt.Errorf("no container cgroup in %q", cgroupPath)
+		}
+		m := containerRegexp.FindStringSubmatch(segments[i+1])
+		if m == nil {
+			return "", "", fmt.Errorf("failed to parse container cgroup %q", segments[i+1])
+		}
+		return podUID, m[1], nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	podUID        = "2c3a1f8e-9d5b-11e7-a5b4-0800279fb07d"
+	systemdPodUID = "2c3a1f8e_9d5b_11e7_a5b4_0800279fb07d"
+	// uid of static pods is a hash of the manifest.
+	staticPodUID = "6e6a2f5a8d1c4b7e9f0a1b2c3d4e5f60"
+	containerID  = "7f8a5e6a2d1b4c3e9f0a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f6a"
+)
+
+func TestParseCgroup(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cgroup string
+		// wantPodUID is empty if the process is not in a pod.
+		wantPodUID string
+	}{
+		{
+			name: "docker cgroupfs v1",
+			cgroup: "12:pids:/kubepods/burstable/pod" + podUID + "/" + containerID + "\n" +
+				"11:memory:/kubepods/burstable/pod" + podUID + "/" + containerID + "\n" +
+				"1:name=systemd:/kubepods/burstable/pod" + podUID + "/" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name: "docker systemd v1",
+			cgroup: "11:cpu,cpuacct:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/docker-" + containerID + ".scope\n" +
+				"1:name=systemd:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/docker-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "docker guaranteed cgroupfs v1",
+			cgroup:     "4:memory:/kubepods/pod" + podUID + "/" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "docker guaranteed systemd v1",
+			cgroup:     "4:memory:/kubepods.slice/kubepods-pod" + systemdPodUID + ".slice/docker-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "containerd cgroupfs v1",
+			cgroup:     "9:devices:/kubepods/besteffort/pod" + podUID + "/" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "containerd systemd v1",
+			cgroup:     "9:devices:/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + systemdPodUID + ".slice/cri-containerd-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "containerd cgroupfs v2",
+			cgroup:     "0::/kubepods/besteffort/pod" + podUID + "/" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "containerd systemd v2",
+			cgroup:     "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod" + systemdPodUID + ".slice/cri-containerd-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "containerd static pod systemd v2",
+			cgroup:     "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + staticPodUID + ".slice/cri-containerd-" + containerID + ".scope\n",
+			wantPodUID: staticPodUID,
+		},
+		{
+			name:       "cri-o cgroupfs v1",
+			cgroup:     "5:cpuset:/kubepods/burstable/pod" + podUID + "/crio-" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "cri-o systemd v1",
+			cgroup:     "5:cpuset:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/crio-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "cri-o cgroupfs v2",
+			cgroup:     "0::/kubepods/burstable/pod" + podUID + "/crio-" + containerID + "\n",
+			wantPodUID: podUID,
+		},
+		{
+			name:       "cri-o systemd v2",
+			cgroup:     "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/crio-" + containerID + ".scope\n",
+			wantPodUID: podUID,
+		},
+		{
+			name: "hybrid v1 and v2",
+			cgroup: "1:name=systemd:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/docker-" + containerID + ".scope\n" +
+				"0::/\n",
+			wantPodUID: podUID,
+		},
+		{
+			name: "user session v1",
+			cgroup: "12:pids:/user.slice/user-1000.slice/session-2.scope\n" +
+				"1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n",
+		},
+		{
+			name:   "user session v2",
+			cgroup: "0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-gnome-bash.scope\n",
+		},
+		{
+			name:   "system service v2",
+			cgroup: "0::/system.slice/docker.service\n",
+		},
+		{
+			name:   "docker container not in a pod",
+			cgroup: "0::/system.slice/docker-" + containerID + ".scope\n",
+		},
+		{
+			name:   "docker container not in a pod cgroupfs",
+			cgroup: "4:memory:/docker/" + containerID + "\n",
+		},
+		{
+			name:   "kubelet v2",
+			cgroup: "0::/system.slice/kubelet.service\n",
+		},
+		{
+			name:   "root v1",
+			cgroup: "12:pids:/\n11:memory:/\n",
+		},
+		{
+			name:   "qos cgroup without pod",
+			cgroup: "0::/kubepods.slice/kubepods-burstable.slice\n",
+		},
+		{
+			name:   "podman container",
+			cgroup: "0::/system.slice/podman-" + containerID + ".scope\n",
+		},
+		{
+			name:   "empty",
+			cgroup: "",
+		},
+	} {
+		gotPodUID, gotContainerID, err := parseCgroup(strings.NewReader(tc.cgroup))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if gotPodUID != tc.wantPodUID {
+			t.Errorf("%s: pod uid = %q, want %q", tc.name, gotPodUID, tc.wantPodUID)
+		}
+		wantContainerID := ""
+		if tc.wantPodUID != "" {
+			wantContainerID = containerID
+		}
+		if gotContainerID != wantContainerID {
+			t.Errorf("%s: container id = %q, want %q", tc.name, gotContainerID, wantContainerID)
+		}
+	}
+}
+
+func TestParseCgroupErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cgroup string
+	}{
+		{"invalid line", "memory/kubepods\n"},
+		{"pod cgroup without container", "0::/kubepods/burstable/pod" + podUID + "\n"},
+		{"conmon of cri-o", "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + systemdPodUID + ".slice/crio-conmon-" + containerID + ".scope\n"},
+		{"short container id", "0::/kubepods/burstable/pod" + podUID + "/" + containerID[:12] + "\n"},
+		{"unknown runtime prefix", "0::/kubepods.slice/kubepods-pod" + systemdPodUID + ".slice/rkt-" + containerID + ".scope\n"},
+	} {
+		if podUID, containerID, err := parseCgroup(strings.NewReader(tc.cgroup)); err == nil {
+			t.Errorf("%s: got pod uid %q, container id %q, want error", tc.name, podUID, containerID)
+		}
+	}
+}
+
+func TestPodRegexps(t *testing.T) {
+	for _, tc := range []struct {
+		segment  string
+		cgroupfs bool
+		systemd  bool
+	}{
+		{"pod" + podUID, true, false},
+		{"pod" + staticPodUID, true, false},
+		{"pod" + podUID + ".slice", false, false},
+		{"podcast", false, false},
+		{"kubepods-burstable-pod" + systemdPodUID + ".slice", false, true},
+		{"kubepods-besteffort-pod" + systemdPodUID + ".slice", false, true},
+		{"kubepods-pod" + systemdPodUID + ".slice", false, true},
+		{"kubepods-pod" + staticPodUID + ".slice", false, true},
+		{"kubepods-burstable.slice", false, false},
+		{"kubepods-burstable-pod" + systemdPodUID + ".scope", false, false},
+		{"kubepods", false, false},
+	} {
+		if got := cgroupfsPodRegexp.MatchString(tc.segment); got != tc.cgroupfs {
+			t.Errorf("cgroupfs pod regexp matches %q: %v, want %v", tc.segment, got, tc.cgroupfs)
+		}
+		if got := systemdPodRegexp.MatchString(tc.segment); got != tc.systemd {
+			t.Errorf("systemd pod regexp matches %q: %v, want %v", tc.segment, got, tc.systemd)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/golang/glog"
+	runtimeapi "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+
+	"k8s.io/coredump-detector/pkg/libcri"
+)
+
+// labels set by kubelet on CRI containers.
+// https://github.com/kubernetes/kubernetes/blob/v1.10.0/pkg/kubelet/types/labels.go
+const (
+	podNameLabel       = "io.kubernetes.pod.name"
+	podNamespaceLabel  = "io.kubernetes.pod.namespace"
+	podUIDLabel        = "io.kubernetes.pod.uid"
+	containerNameLabel = "io.kubernetes.container.name"
+)
+
+type criResolver struct {
+	client libcri.Client
+}
+
+// NewCRIResolver returns a resolver which looks up the process in every
+// running kubernetes container of the CRI runtime.
+func NewCRIResolver(client libcri.Client) RuntimeResolver {
+	return &criResolver{client: client}
+}
+
+func (r *criResolver) Resolve(hostPid string) (*Container, error) {
+	cgroup, err := readCgroup(hostPid)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := r.client.ListContainers(&runtimeapi.ContainerFilter{
+		State: &runtimeapi.ContainerStateValue{State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		// not a k8s container
+		if _, ok := c.Labels[podUIDLabel]; !ok {
+			continue
+		}
+		pid, err := r.containerPid(c.Id)
+		if err != nil {
+			glog.Warningf("failed to get pid of container %s: %v", c.Id, err)
+			continue
+		}
+		// processes of a container share the cgroups of the container.
+		containerCgroup, err := readCgroup(strconv.Itoa(pid))
+		if err != nil {
+			glog.Warningf("failed to get cgroup of container %s: %v", c.Id, err)
+			continue
+		}
+		if containerCgroup == cgroup {
+			return containerFromLabels(c.Id, c.Labels), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *criResolver) ResolveID(containerID string) (*Container, error) {
+	resp, err := r.client.ContainerStatus(containerID, false)
+	if err != nil {
+		return nil, err
+	}
+	status := resp.Status
+	if status == nil {
+		return nil, fmt.Errorf("status of container %s is not found", containerID)
+	}
+	// not a k8s container
+	if _, ok := status.Labels[podUIDLabel]; !ok {
+		return nil, nil
+	}
+	return containerFromLabels(status.Id, status.Labels), nil
+}
+
+func containerFromLabels(id string, labels map[string]string) *Container {
+	return &Container{
+		ID:            id,
+		ContainerName: labels[containerNameLabel],
+		Pod:           labels[podNameLabel],
+		Namespace:     labels[podNamespaceLabel],
+		Uid:           labels[podUIDLabel],
+	}
+}
+
+// containerPid returns the host pid of the container's init process, which
+// is reported in the verbose info by containerd and cri-o.
+func (r *criResolver) containerPid(containerID string) (int, error) {
+	resp, err := r.client.ContainerStatus(containerID, true)
+	if err != nil {
+		return 0, err
+	}
+	info := struct {
+		Pid int `json:"pid"`
+	}{}
+	if err := json.Unmarshal([]byte(resp.Info["info"]), &info); err != nil {
+		return 0, err
+	}
+	if info.Pid == 0 {
+		return 0, fmt.Errorf("pid not found in container info")
+	}
+	return info.Pid, nil
+}
+
+func readCgroup(pid string) (string, error) {
+	data, err := ioutil.ReadFile("/proc/" + pid + "/cgroup")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
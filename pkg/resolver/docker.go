@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+
+	"k8s.io/coredump-detector/pkg/libdocker"
+)
+
+type dockerResolver struct {
+	client libdocker.Client
+}
+
+// NewDockerResolver returns a resolver which looks up the process in every
+// kubernetes container created by dockershim.
+func NewDockerResolver(client libdocker.Client) RuntimeResolver {
+	return &dockerResolver{client: client}
+}
+
+func (r *dockerResolver) Resolve(hostPid string) (*Container, error) {
+	containers, err := r.client.ContainerList(types.ContainerListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		for _, name := range c.Names {
+			// a k8s container
+			// format of container name:
+			// https://github.com/kubernetes/kubernetes/blob/v1.8.0-beta.1/pkg/kubelet/dockershim/naming.go
+			if strings.HasPrefix(name, "/k8s") {
+				body, err := r.client.ContainerTop(c.ID)
+				if err != nil {
+					return nil, err
+				}
+				index := 0
+				// get the index of PID
+				for i, t := range body.Titles {
+					if strings.EqualFold(t, "PID") {
+						index = i
+					}
+				}
+				for _, p := range body.Processes {
+					if p[index] == hostPid {
+						//a progress in k8s pod.
+						container, err := parseContainerName(name)
+						if err != nil {
+							return nil, err
+						}
+						container.ID = c.ID
+						return container, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *dockerResolver) ResolveID(containerID string) (*Container, error) {
+	info, err := r.client.ContainerInspect(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(info.Name, "/k8s") {
+		return nil, nil
+	}
+	container, err := parseContainerName(info.Name)
+	if err != nil {
+		return nil, err
+	}
+	container.ID = info.ID
+	return container, nil
+}
+
+func parseContainerName(name string) (*Container, error) {
+	// Docker adds a "/" prefix to names. so trim it.
+	name = strings.TrimPrefix(name, "/")
+
+	parts := strings.Split(name, "_")
+	// Tolerate the random suffix.
+	// TODO: Remove 7 field case when docker 1.11 is deprecated.
+	if len(parts) != 6 && len(parts) != 7 {
+		return nil, fmt.Errorf("failed to parse the container name: %q", name)
+	}
+	return &Container{
+		ContainerName: parts[1],
+		Pod:           parts[2],
+		Namespace:     parts[3],
+		Uid:           parts[4],
+	}, nil
+}
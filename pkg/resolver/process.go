@@ -0,0 +1,105 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PidfdPid returns the pid of the process referred by the pidfd, as seen in
+// the PID namespace of the caller. It's -1 if the process exited, and 0 if
+// the process is not in the PID namespace of the caller.
+func PidfdPid(pidfd int) (int, error) {
+	fields, err := statusFields("/proc/self/fdinfo/"+strconv.Itoa(pidfd), "Pid:")
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) != 1 {
+		return 0, fmt.Errorf("fd %d is not a pidfd", pidfd)
+	}
+	return strconv.Atoi(fields[0])
+}
+
+// Process is the information of a process read from /proc.
+type Process struct {
+	// Comm is the command name, the executable filename without path
+	// prefix as %e of core_pattern.
+	Comm string
+	// NSPid is the pid in the PID namespace where the process resides.
+	NSPid int
+}
+
+// ReadProcess reads the information of the process from /proc.
+func ReadProcess(pid int) (*Process, error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	comm, err := ioutil.ReadFile(dir + "/comm")
+	if err != nil {
+		return nil, err
+	}
+	// NSpid lists the pids from the outermost namespace to the innermost.
+	fields, err := statusFields(dir+"/status", "NSpid:")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no NSpid in %s/status", dir)
+	}
+	nsPid, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &Process{
+		Comm:  strings.TrimSuffix(string(comm), "\n"),
+		NSPid: nsPid,
+	}, nil
+}
+
+// ReadCmdline reads the command line of the process from /proc, its
+// arguments are separated by spaces.
+func ReadCmdline(pid string) (string, error) {
+	cmdline, err := ioutil.ReadFile("/proc/" + pid + "/cmdline")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"), " "), nil
+}
+
+// statusFields returns the fields of the line with the key in a status file
+// like /proc/<pid>/status.
+func statusFields(filename, key string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[0] == key {
+			return fields[1:], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no %s in %s", key, filename)
+}
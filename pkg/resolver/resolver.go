@@ -0,0 +1,96 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+
+	"k8s.io/coredump-detector/pkg/libcri"
+	"k8s.io/coredump-detector/pkg/libdocker"
+)
+
+const (
+	// DockerRuntime resolves containers with the docker api.
+	DockerRuntime = "docker"
+	// RemoteRuntime resolves containers with the CRI api, e.g. containerd and cri-o.
+	RemoteRuntime = "remote"
+)
+
+// Container is the kubernetes container where a process runs.
+type Container struct {
+	ID            string
+	ContainerName string
+	Pod           string
+	Namespace     string
+	Uid           string
+}
+
+// Resolver finds the kubernetes container of a process.
+type Resolver interface {
+	// Resolve returns the container where the process runs, hostPid is the
+	// PID as seen in the initial PID namespace. It returns nil if the process
+	// does not run in a kubernetes container.
+	Resolve(hostPid string) (*Container, error)
+}
+
+// RuntimeResolver finds the kubernetes container with the container runtime api.
+type RuntimeResolver interface {
+	Resolver
+	// ResolveID returns the container with the container id. It returns nil
+	// if it's not a kubernetes container.
+	ResolveID(containerID string) (*Container, error)
+}
+
+// NewResolverOrDie returns the cgroup resolver backed by the container
+// runtime. An empty endpoint means the default endpoint of the runtime.
+func NewResolverOrDie(runtime, endpoint string) Resolver {
+	return NewCgroupResolver(NewRuntimeResolverOrDie(runtime, endpoint))
+}
+
+// NewRuntimeResolverOrDie returns the resolver of the container runtime. An
+// empty endpoint means the default endpoint of the runtime.
+func NewRuntimeResolverOrDie(runtime, endpoint string) RuntimeResolver {
+	switch runtime {
+	case DockerRuntime:
+		return NewDockerResolver(libdocker.NewClientOrDie(endpoint))
+	case RemoteRuntime:
+		if endpoint == "" {
+			panic(fmt.Errorf("container runtime endpoint is required by %q runtime", RemoteRuntime))
+		}
+		return NewCRIResolver(libcri.NewClientOrDie(endpoint))
+	}
+	panic(fmt.Errorf("unsupported container runtime %q", runtime))
+}
+
+type idResolver struct {
+	runtime     RuntimeResolver
+	containerID string
+}
+
+// NewIDResolver returns a resolver of processes which already exited, it
+// looks up the container by the id read from the cgroup when the process
+// was running. An empty id means the process is not in a kubernetes pod.
+func NewIDResolver(runtime RuntimeResolver, containerID string) Resolver {
+	return &idResolver{runtime: runtime, containerID: containerID}
+}
+
+func (r *idResolver) Resolve(hostPid string) (*Container, error) {
+	if r.containerID == "" {
+		return nil, nil
+	}
+	return r.runtime.ResolveID(r.containerID)
+}
@@ -0,0 +1,444 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/apiextensions"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/sparse"
+)
+
+// maxRetries is the number of times a coredump is retried before it's
+// dropped out of the queue, it's queued again on resync.
+const maxRetries = 15
+
+// CoredumpSaver watches Coredump objects of this node, and moves the cached
+// coredump files to persistent volume once they are allowed by the controller.
+type CoredumpSaver struct {
+	client  apiextensions.CoredumpClient
+	options *options.CoredumpSaverOptions
+
+	// store of Coredump objects of this node.
+	store cache.Store
+	// queue of coredumps to sync. Coredumps are synced by one worker, so a
+	// file is never moved twice at the same time.
+	queue workqueue.RateLimitingInterface
+}
+
+func NewCoredumpSaver(client apiextensions.CoredumpClient, options *options.CoredumpSaverOptions) *CoredumpSaver {
+	return &CoredumpSaver{
+		client:  client,
+		options: options,
+		queue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "coredumps"),
+	}
+}
+
+// Run starts the saver and blocks until the context is done.
+func (s *CoredumpSaver) Run(ctx context.Context) error {
+	defer utilruntime.HandleCrash()
+	defer s.queue.ShutDown()
+
+	if s.options.NodeName == "" {
+		return fmt.Errorf("node name is required")
+	}
+	maxCacheSize, err := ParseMaxCacheSize(s.options.MaxCacheSize)
+	if err != nil {
+		return err
+	}
+	glog.Infof("Watch Coredump objects of node %s", s.options.NodeName)
+
+	selector := labels.SelectorFromSet(labels.Set{coredump.CoredumpNodeLabel: s.options.NodeName})
+	store, controller := cache.NewInformer(
+		s.client.ListWatchCoredumps(selector),
+		&coredump.Coredump{},
+		// coredumps dropped out of the queue are retried on resync.
+		s.options.ResyncPeriod,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: s.enqueue,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				s.enqueue(newObj)
+			},
+		})
+	s.store = store
+
+	go controller.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), controller.HasSynced) {
+		return fmt.Errorf("failed to wait for coredump cache to sync")
+	}
+	go wait.Until(s.runWorker, time.Second, ctx.Done())
+
+	sw := &sweeper{
+		saver:        s,
+		store:        store,
+		maxCacheSize: maxCacheSize,
+	}
+	go wait.Until(func() {
+		// files are only orphaned if the store is complete.
+		if controller.HasSynced() {
+			sw.sweep()
+		}
+	}, s.options.SweepPeriod, ctx.Done())
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// enqueue adds the key of a coredump to the queue.
+func (s *CoredumpSaver) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %v", obj, err))
+		return
+	}
+	s.queue.Add(key)
+}
+
+func (s *CoredumpSaver) runWorker() {
+	for s.processNextWorkItem() {
+	}
+}
+
+func (s *CoredumpSaver) processNextWorkItem() bool {
+	key, quit := s.queue.Get()
+	if quit {
+		return false
+	}
+	defer s.queue.Done(key)
+
+	err := s.syncCoredump(key.(string))
+	s.handleErr(err, key)
+	return true
+}
+
+func (s *CoredumpSaver) handleErr(err error, key interface{}) {
+	if err == nil {
+		s.queue.Forget(key)
+		return
+	}
+
+	if s.queue.NumRequeues(key) < maxRetries {
+		glog.V(2).Infof("Error syncing coredump %v: %v", key, err)
+		s.queue.AddRateLimited(key)
+		return
+	}
+
+	utilruntime.HandleError(fmt.Errorf("dropping coredump %q out of the queue: %v", key, err))
+	s.queue.Forget(key)
+}
+
+// syncCoredump saves the coredump of key if it's allowed, and removes its
+// file if it's being deleted.
+func (s *CoredumpSaver) syncCoredump(key string) error {
+	obj, exists, err := s.store.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		// the coredump is deleted, its file is removed by the finalizer.
+		return nil
+	}
+	cd := obj.(*coredump.Coredump)
+	if cd.ObjectMeta.DeletionTimestamp != nil {
+		return s.cleanup(cd)
+	}
+	switch cd.Status.State {
+	case coredump.CoredumpStateStateAllowed:
+		return s.save(cd)
+	case coredump.CoredumpStateProcessed:
+		// coredumps saved by former versions have no finalizer.
+		if !hasFinalizer(cd, coredump.FileCleanupFinalizer) {
+			cdCopy := cd.DeepCopy()
+			cdCopy.ObjectMeta.Finalizers = append(cdCopy.ObjectMeta.Finalizers, coredump.FileCleanupFinalizer)
+			if _, err := s.client.UpdateCoredump(cdCopy); err != nil {
+				return fmt.Errorf("failed to add finalizer: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// save moves the file of an allowed coredump to the persistent volume, and
+// updates its state. If the file is already in the persistent volume, e.g.
+// only the status failed to be updated, it isn't moved again.
+func (s *CoredumpSaver) save(cd *coredump.Coredump) error {
+	var volume string
+	var allocatedSize int64
+	var err error
+	if fi, statErr := os.Stat(s.persistentPath(cd)); cd.Spec.Volume != "" && statErr == nil {
+		volume, allocatedSize = cd.Spec.Volume, sparse.AllocatedSize(fi)
+	} else {
+		volume, allocatedSize, err = s.saveToPersistentVolume(cd)
+	}
+	status := coredump.CoredumpStatus{
+		State:   coredump.CoredumpStateProcessed,
+		Message: "Saved to persistent volume",
+	}
+	if err != nil {
+		glog.Errorf("Failed to save %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+		status = coredump.CoredumpStatus{
+			State:   coredump.CoredumpStateFailed,
+			Message: fmt.Sprintf("Failed to save to persistent volume: %v", err),
+		}
+	} else {
+		// status is a subresource, spec and status are updated separately.
+		cd, err = s.updateCoredump(cd, s.client.UpdateCoredump, func(cdCopy *coredump.Coredump) {
+			cdCopy.Spec.Volume = volume
+			// the persistent volume may not support sparse files.
+			cdCopy.Spec.AllocatedSize = resource.NewQuantity(allocatedSize, resource.BinarySI)
+			// the file is removed before the object is deleted.
+			if !hasFinalizer(cdCopy, coredump.FileCleanupFinalizer) {
+				cdCopy.ObjectMeta.Finalizers = append(cdCopy.ObjectMeta.Finalizers, coredump.FileCleanupFinalizer)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update: %v", err)
+		}
+	}
+	_, err = s.updateCoredump(cd, s.client.UpdateCoredumpStatus, func(cdCopy *coredump.Coredump) {
+		cdCopy.Status = status
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update status: %v", err)
+	}
+	glog.Infof("Updated %s/%s to state %s", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, status.State)
+	return nil
+}
+
+// updateCoredump applies change to a copy of cd, and writes it with update.
+// On conflict, e.g. the controller set an annotation meanwhile, change is
+// applied again to the latest object.
+func (s *CoredumpSaver) updateCoredump(cd *coredump.Coredump, update func(*coredump.Coredump) (*coredump.Coredump, error), change func(*coredump.Coredump)) (*coredump.Coredump, error) {
+	// NEVER modify objects from the store. It's a read-only, local cache.
+	latest := cd
+	var updated *coredump.Coredump
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cdCopy := latest.DeepCopy()
+		change(cdCopy)
+		var err error
+		updated, err = update(cdCopy)
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+		got, getErr := s.client.GetCoredump(cd.ObjectMeta.Namespace, cd.ObjectMeta.Name)
+		if getErr != nil {
+			return getErr
+		}
+		latest = got
+		return err
+	})
+	if err != nil {
+		return cd, err
+	}
+	return updated, nil
+}
+
+// cleanup removes the file of a coredump being deleted, from the host cache
+// or the persistent volume, and then removes the finalizer, so the object
+// disappears. If the removal fails, the FileCleanupFailed condition is set,
+// and the removal is retried.
+func (s *CoredumpSaver) cleanup(cd *coredump.Coredump) error {
+	if !hasFinalizer(cd, coredump.FileCleanupFinalizer) {
+		return nil
+	}
+	cachePath := dump.CachePath(s.options.DumpDir, cd)
+	files := []string{cachePath, cachePath + dump.ModulesExt}
+	if cd.Status.State == coredump.CoredumpStateProcessed {
+		files = append(files, s.persistentPath(cd), s.persistentPath(cd)+dump.ModulesExt)
+	}
+	for _, file := range files {
+		err := os.Remove(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			s.setCleanupFailed(cd, err)
+			return fmt.Errorf("failed to remove %s: %v", file, err)
+		}
+		glog.Infof("Removed %s of deleted coredump %s/%s", file, cd.ObjectMeta.Namespace, cd.ObjectMeta.Name)
+	}
+	cdCopy := cd.DeepCopy()
+	cdCopy.ObjectMeta.Finalizers = removeFinalizer(cdCopy.ObjectMeta.Finalizers, coredump.FileCleanupFinalizer)
+	if _, err := s.client.UpdateCoredump(cdCopy); err != nil {
+		return fmt.Errorf("failed to remove finalizer: %v", err)
+	}
+	return nil
+}
+
+// setCleanupFailed sets the FileCleanupFailed condition of cd, so users know
+// why the coredump isn't deleted.
+func (s *CoredumpSaver) setCleanupFailed(cd *coredump.Coredump, err error) {
+	condition := coredump.CoredumpCondition{
+		Type:               coredump.CoredumpFileCleanupFailed,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "RemoveFailed",
+		Message:            err.Error(),
+	}
+	cdCopy := cd.DeepCopy()
+	conditions := []coredump.CoredumpCondition{condition}
+	for _, c := range cdCopy.Status.Conditions {
+		if c.Type != condition.Type {
+			conditions = append(conditions, c)
+			continue
+		}
+		if c.Status == condition.Status && c.Message == condition.Message {
+			// already set
+			return
+		}
+		if c.Status == condition.Status {
+			conditions[0].LastTransitionTime = c.LastTransitionTime
+		}
+	}
+	cdCopy.Status.Conditions = conditions
+	if _, err := s.client.UpdateCoredumpStatus(cdCopy); err != nil {
+		glog.Errorf("Failed to update status of %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+	}
+}
+
+func hasFinalizer(cd *coredump.Coredump, finalizer string) bool {
+	for _, f := range cd.ObjectMeta.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string, finalizer string) []string {
+	var result []string
+	for _, f := range finalizers {
+		if f != finalizer {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// persistentPath returns the path of the coredump file in the persistent
+// volume.
+func (s *CoredumpSaver) persistentPath(cd *coredump.Coredump) string {
+	dirname := dump.CacheDir(cd.ObjectMeta.Namespace, cd.Spec.Pod, string(cd.Spec.Uid), cd.Spec.ContainerName)
+	return path.Join(s.options.PVDir, dirname, path.Base(dump.CachePath(s.options.DumpDir, cd)))
+}
+
+// saveToPersistentVolume moves the cached coredump file to the persistent
+// volume, and returns the volume in format "<volume name>:<dir in volume>"
+// and the disk space allocated for the file in the volume.
+func (s *CoredumpSaver) saveToPersistentVolume(cd *coredump.Coredump) (string, int64, error) {
+	dirname := dump.CacheDir(cd.ObjectMeta.Namespace, cd.Spec.Pod, string(cd.Spec.Uid), cd.Spec.ContainerName)
+	src := dump.CachePath(s.options.DumpDir, cd)
+	dest := s.persistentPath(cd)
+	volume := s.options.VolumeName + ":/" + dirname
+
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		// the file may have been moved before a failed update, it's retried on resync.
+		if fi, err := os.Stat(dest); err == nil {
+			moveModules(src, dest)
+			return volume, sparse.AllocatedSize(fi), nil
+		}
+		return "", 0, fmt.Errorf("coredump file %s not found", src)
+	}
+	if err := os.MkdirAll(path.Dir(dest), 0775); err != nil {
+		return "", 0, err
+	}
+	// we need to do tenant isolation for dump files, like using nfs access
+	// permissions, or publish core files in web application.
+	if err := moveFile(src, dest); err != nil {
+		return "", 0, err
+	}
+	glog.Infof("Moved %s to %s", src, dest)
+	moveModules(src, dest)
+	fi, err := os.Stat(dest)
+	if err != nil {
+		return "", 0, err
+	}
+	return volume, sparse.AllocatedSize(fi), nil
+}
+
+// moveModules moves the sidecar file of the coredump file along with it, if
+// there is one. The coredump is saved even if it fails.
+func moveModules(src, dest string) {
+	if _, err := os.Stat(src + dump.ModulesExt); err != nil {
+		return
+	}
+	if err := moveFile(src+dump.ModulesExt, dest+dump.ModulesExt); err != nil {
+		glog.Errorf("Failed to move %s to %s: %v", src+dump.ModulesExt, dest+dump.ModulesExt, err)
+	}
+}
+
+// moveFile renames src to dest, the file is copied if they are not in the
+// same filesystem. Holes of sparse files are kept in the copy.
+func moveFile(src, dest string) error {
+	err := os.Rename(src, dest)
+	if err == nil {
+		return nil
+	}
+	if linkErr, ok := err.(*os.LinkError); !ok || linkErr.Err != syscall.EXDEV {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	// copy to a temporary file, so a partial file is never seen as saved.
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := sparse.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/cmd/options"
+	"k8s.io/coredump-detector/pkg/dump"
+)
+
+// fakeCoredumpClient keeps coredumps in memory, and checks their resource
+// version on update as the apiserver does. onUpdate, if set, is called
+// before each update, e.g. to change the object as another writer.
+type fakeCoredumpClient struct {
+	coredumps map[string]*coredump.Coredump
+	version   int
+	onUpdate  func(stored *coredump.Coredump)
+}
+
+func newFakeCoredumpClient(coredumps ...*coredump.Coredump) *fakeCoredumpClient {
+	c := &fakeCoredumpClient{coredumps: map[string]*coredump.Coredump{}}
+	for _, cd := range coredumps {
+		c.CreateCoredump(cd, cd.ObjectMeta.Namespace)
+	}
+	return c
+}
+
+func (c *fakeCoredumpClient) store(cd *coredump.Coredump) *coredump.Coredump {
+	c.version++
+	stored := cd.DeepCopy()
+	stored.ObjectMeta.ResourceVersion = strconv.Itoa(c.version)
+	c.coredumps[cd.ObjectMeta.Namespace+"/"+cd.ObjectMeta.Name] = stored
+	return stored.DeepCopy()
+}
+
+func (c *fakeCoredumpClient) CreateCoredump(cd *coredump.Coredump, namespace string) (*coredump.Coredump, error) {
+	return c.store(cd), nil
+}
+
+func (c *fakeCoredumpClient) GetCoredump(namespace, name string) (*coredump.Coredump, error) {
+	stored, ok := c.coredumps[namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(coredump.Resource("coredumps"), name)
+	}
+	return stored.DeepCopy(), nil
+}
+
+// update stores cd with the status of the stored object, or only the status
+// of cd if status is true.
+func (c *fakeCoredumpClient) update(cd *coredump.Coredump, status bool) (*coredump.Coredump, error) {
+	stored, ok := c.coredumps[cd.ObjectMeta.Namespace+"/"+cd.ObjectMeta.Name]
+	if !ok {
+		return nil, apierrors.NewNotFound(coredump.Resource("coredumps"), cd.ObjectMeta.Name)
+	}
+	if hook := c.onUpdate; hook != nil {
+		hook(stored)
+	}
+	if stored.ObjectMeta.ResourceVersion != cd.ObjectMeta.ResourceVersion {
+		return nil, apierrors.NewConflict(coredump.Resource("coredumps"), cd.ObjectMeta.Name, nil)
+	}
+	if status {
+		updated := stored.DeepCopy()
+		updated.Status = cd.Status
+		return c.store(updated), nil
+	}
+	updated := cd.DeepCopy()
+	updated.Status = stored.Status
+	return c.store(updated), nil
+}
+
+func (c *fakeCoredumpClient) UpdateCoredump(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	return c.update(cd, false)
+}
+
+func (c *fakeCoredumpClient) UpdateCoredumpStatus(cd *coredump.Coredump) (*coredump.Coredump, error) {
+	return c.update(cd, true)
+}
+
+func (c *fakeCoredumpClient) ListCoredumpPolicies(namespace string) (*coredump.CoredumpPolicyList, error) {
+	return &coredump.CoredumpPolicyList{}, nil
+}
+
+func (c *fakeCoredumpClient) ListCoredumpQuotas(namespace string) (*coredump.CoredumpQuotaList, error) {
+	return &coredump.CoredumpQuotaList{}, nil
+}
+
+func (c *fakeCoredumpClient) ListWatchCoredumps(selector labels.Selector) cache.ListerWatcher {
+	return nil
+}
+
+func newCoredump(name string, state coredump.CoredumpState) *coredump.Coredump {
+	return &coredump.Coredump{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: coredump.CoredumpSpec{
+			Pod:           "web",
+			Uid:           "uid",
+			ContainerName: "app",
+		},
+		Status: coredump.CoredumpStatus{State: state},
+	}
+}
+
+func newTestSaver(t *testing.T, client *fakeCoredumpClient) (*CoredumpSaver, func()) {
+	dir, err := ioutil.TempDir("", "saver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCoredumpSaver(client, &options.CoredumpSaverOptions{
+		DumpDir:    filepath.Join(dir, "cache"),
+		PVDir:      filepath.Join(dir, "pv"),
+		VolumeName: "pv",
+		NodeName:   "node1",
+	}), func() { os.RemoveAll(dir) }
+}
+
+func writeFile(t *testing.T, name, content string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveRetriesOnConflict(t *testing.T) {
+	client := newFakeCoredumpClient(newCoredump("core", coredump.CoredumpStateStateAllowed))
+	s, cleanup := newTestSaver(t, client)
+	defer cleanup()
+	cd, _ := client.GetCoredump("default", "core")
+	writeFile(t, dump.CachePath(s.options.DumpDir, cd), "core")
+	// the controller updates the object once, after the saver read it.
+	client.onUpdate = func(stored *coredump.Coredump) {
+		client.onUpdate = nil
+		stored.ObjectMeta.Annotations = map[string]string{coredump.ExpireTimeAnnotation: "2017-10-02T00:00:00Z"}
+		client.version++
+		stored.ObjectMeta.ResourceVersion = strconv.Itoa(client.version)
+	}
+
+	if err := s.save(cd); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	saved, _ := client.GetCoredump("default", "core")
+	if saved.Status.State != coredump.CoredumpStateProcessed {
+		t.Errorf("state = %s (%s), want %s", saved.Status.State, saved.Status.Message, coredump.CoredumpStateProcessed)
+	}
+	if saved.Spec.Volume != "pv:/default/web-uid/app" || !hasFinalizer(saved, coredump.FileCleanupFinalizer) {
+		t.Errorf("volume = %q, finalizers = %v, want the volume and the file cleanup finalizer", saved.Spec.Volume, saved.ObjectMeta.Finalizers)
+	}
+	if saved.ObjectMeta.Annotations[coredump.ExpireTimeAnnotation] == "" {
+		t.Error("the annotation set by the controller is lost")
+	}
+	if content, err := ioutil.ReadFile(s.persistentPath(saved)); err != nil || string(content) != "core" {
+		t.Errorf("file in persistent volume = %q, %v, want %q", content, err, "core")
+	}
+}
+
+func TestSaveFileAlreadyInVolume(t *testing.T) {
+	// the file was moved and the spec updated, but the status failed to be
+	// updated.
+	cd := newCoredump("core", coredump.CoredumpStateStateAllowed)
+	cd.Spec.Volume = "pv:/default/web-uid/app"
+	client := newFakeCoredumpClient(cd)
+	s, cleanup := newTestSaver(t, client)
+	defer cleanup()
+	cd, _ = client.GetCoredump("default", "core")
+	writeFile(t, s.persistentPath(cd), "saved")
+	// e.g. a file of the same name written again in host cache.
+	writeFile(t, dump.CachePath(s.options.DumpDir, cd), "cached")
+
+	if err := s.save(cd); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	saved, _ := client.GetCoredump("default", "core")
+	if saved.Status.State != coredump.CoredumpStateProcessed {
+		t.Errorf("state = %s (%s), want %s", saved.Status.State, saved.Status.Message, coredump.CoredumpStateProcessed)
+	}
+	if content, err := ioutil.ReadFile(s.persistentPath(saved)); err != nil || string(content) != "saved" {
+		t.Errorf("file in persistent volume = %q, %v, want it not moved again", content, err)
+	}
+}
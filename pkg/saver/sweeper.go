@@ -0,0 +1,209 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/tools/cache"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/sparse"
+)
+
+// cacheFile is a file in the host cache.
+type cacheFile struct {
+	path    string
+	modTime time.Time
+	// size is the disk space allocated for the file.
+	size int64
+	// coredump is the Coredump object of the file, nil if there is none.
+	coredump *coredump.Coredump
+	// sidecar is true if the file is the sidecar of a coredump file, which
+	// follows the coredump file.
+	sidecar bool
+}
+
+// sweeper removes files in the host cache which won't be saved, and keeps
+// the total size of the host cache under the limit.
+type sweeper struct {
+	saver *CoredumpSaver
+	// store of Coredump objects of this node.
+	store cache.Store
+	// maxCacheSize is the max total size of the host cache, 0 means
+	// unlimited.
+	maxCacheSize int64
+}
+
+// ParseMaxCacheSize converts the value of max cache size option to bytes, 0
+// means unlimited.
+func ParseMaxCacheSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max cache size %q: %v", value, err)
+	}
+	if q.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid max cache size %q: must be positive", value)
+	}
+	return q.Value(), nil
+}
+
+// sweep cross-references the files in the host cache with the Coredump
+// objects of this node. Files older than the grace period are removed if
+// their coredump is denied, already saved, or has no Coredump object, e.g.
+// it's deleted or failed to be created. The grace period leaves time for
+// coredump-detector to create the object of a file just written, and files
+// with a pending registration are kept until coredump-agent creates their
+// objects. Then, if the host cache is larger than the limit, the oldest
+// files are evicted. Coredumps of processes not in pods, in the "others"
+// directory, never have Coredump objects. They're neither removed nor
+// counted in the host cache, only the min free space of coredump-detector
+// limits them.
+func (sw *sweeper) sweep() {
+	options := sw.saver.options
+	byPath := map[string]*coredump.Coredump{}
+	for _, obj := range sw.store.List() {
+		cd := obj.(*coredump.Coredump)
+		byPath[dump.CachePath(options.DumpDir, cd)] = cd
+	}
+
+	others := filepath.Join(options.DumpDir, dump.OthersDir)
+	var files []*cacheFile
+	err := filepath.Walk(options.DumpDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() && path == others {
+			return filepath.SkipDir
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		sidecar := strings.HasSuffix(path, dump.ModulesExt)
+		files = append(files, &cacheFile{
+			path:     path,
+			modTime:  fi.ModTime(),
+			size:     sparse.AllocatedSize(fi),
+			coredump: byPath[strings.TrimSuffix(path, dump.ModulesExt)],
+			sidecar:  sidecar,
+		})
+		return nil
+	})
+	if err != nil {
+		glog.Errorf("Failed to walk %s: %v", options.DumpDir, err)
+		return
+	}
+
+	pending := map[string]bool{}
+	for _, file := range files {
+		if strings.HasSuffix(file.path, dump.PendingExt) {
+			pending[file.path] = true
+			pending[strings.TrimSuffix(file.path, dump.PendingExt)] = true
+		}
+	}
+
+	now := time.Now()
+	var cacheSize int64
+	var evictable []*cacheFile
+	for _, file := range files {
+		if pending[strings.TrimSuffix(file.path, dump.ModulesExt)] || now.Sub(file.modTime) < options.OrphanGracePeriod {
+			cacheSize += file.size
+			continue
+		}
+		switch {
+		case file.coredump == nil:
+			sw.remove(file, "it has no coredump object")
+		case file.coredump.Status.State == coredump.CoredumpStateDenied:
+			sw.remove(file, "its coredump is denied")
+		case file.coredump.Status.State == coredump.CoredumpStateProcessed:
+			sw.remove(file, "its coredump is already saved")
+		default:
+			cacheSize += file.size
+			// allowed coredumps are moved soon, and sidecars are removed
+			// after their coredumps are evicted.
+			if file.coredump.Status.State != coredump.CoredumpStateStateAllowed && !file.sidecar {
+				evictable = append(evictable, file)
+			}
+		}
+	}
+
+	if sw.maxCacheSize <= 0 || cacheSize <= sw.maxCacheSize {
+		return
+	}
+	sort.Slice(evictable, func(i, j int) bool {
+		return evictable[i].modTime.Before(evictable[j].modTime)
+	})
+	for _, file := range evictable {
+		if cacheSize <= sw.maxCacheSize {
+			break
+		}
+		if sw.evict(file) {
+			cacheSize -= file.size
+		}
+	}
+	if cacheSize > sw.maxCacheSize {
+		glog.Warningf("Host cache size %d exceeds %d, no more coredumps can be evicted", cacheSize, sw.maxCacheSize)
+	}
+}
+
+// remove removes the file, and the directories of the container and the pod
+// if they're empty.
+func (sw *sweeper) remove(file *cacheFile, reason string) bool {
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		glog.Errorf("Failed to remove %s: %v", file.path, err)
+		return false
+	}
+	glog.Infof("Removed %s from host cache, %s", file.path, reason)
+	// <namespace>/<pod>-<uid>/<container>/<file>
+	dir := filepath.Dir(file.path)
+	for i := 0; i < 3 && dir != filepath.Clean(sw.saver.options.DumpDir); i++ {
+		if os.Remove(dir) != nil {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return true
+}
+
+// evict marks the coredump of the file as Denied, so it's no longer counted
+// in quota, and then removes the file. The coredump is skipped if it's
+// updated by others meanwhile, e.g. allowed by the controller.
+func (sw *sweeper) evict(file *cacheFile) bool {
+	cd := file.coredump.DeepCopy()
+	cd.Status.State = coredump.CoredumpStateDenied
+	cd.Status.Message = fmt.Sprintf("Evicted from host cache of node %s, the cache exceeds %s",
+		sw.saver.options.NodeName, resource.NewQuantity(sw.maxCacheSize, resource.BinarySI).String())
+	if _, err := sw.saver.client.UpdateCoredumpStatus(cd); err != nil {
+		glog.Errorf("Failed to update status of %s/%s: %v", cd.ObjectMeta.Namespace, cd.ObjectMeta.Name, err)
+		return false
+	}
+	return sw.remove(file, "the host cache is full")
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package saver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+
+	coredump "k8s.io/coredump-detector/apis/coredump/v1alpha1"
+	"k8s.io/coredump-detector/pkg/dump"
+	"k8s.io/coredump-detector/pkg/sparse"
+)
+
+// newTestSweeper returns a sweeper of the coredumps in client, with a grace
+// period of an hour.
+func newTestSweeper(t *testing.T, client *fakeCoredumpClient, maxCacheSize int64) (*sweeper, func()) {
+	s, cleanup := newTestSaver(t, client)
+	s.options.OrphanGracePeriod = time.Hour
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, cd := range client.coredumps {
+		store.Add(cd.DeepCopy())
+	}
+	return &sweeper{saver: s, store: store, maxCacheSize: maxCacheSize}, cleanup
+}
+
+// writeAged writes a file of 4KiB modified age ago, and returns its
+// allocated size.
+func writeAged(t *testing.T, name string, age time.Duration) int64 {
+	writeFile(t, name, strings.Repeat("x", 4096))
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sparse.AllocatedSize(fi)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func TestSweep(t *testing.T) {
+	client := newFakeCoredumpClient(
+		newCoredump("denied", coredump.CoredumpStateDenied),
+		newCoredump("saved", coredump.CoredumpStateProcessed),
+		newCoredump("allowed", coredump.CoredumpStateStateAllowed),
+		newCoredump("created", coredump.CoredumpStateCreated),
+		newCoredump("young-denied", coredump.CoredumpStateDenied),
+	)
+	sw, cleanup := newTestSweeper(t, client, 0)
+	defer cleanup()
+	dumpDir := sw.saver.options.DumpDir
+	containerDir := filepath.Join(dumpDir, dump.CacheDir("default", "web", "uid", "app"))
+	path := func(name string) string {
+		return filepath.Join(containerDir, name)
+	}
+
+	files := []struct {
+		path     string
+		young    bool
+		wantKept bool
+	}{
+		{path: path("denied")},
+		{path: path("denied") + dump.ModulesExt},
+		{path: path("saved")},
+		{path: path("orphan")},
+		{path: path("young-denied"), young: true, wantKept: true},
+		{path: path("young-orphan"), young: true, wantKept: true},
+		{path: path("allowed"), wantKept: true},
+		{path: path("created"), wantKept: true},
+		{path: path("created") + dump.ModulesExt, wantKept: true},
+		// the object of a pending registration isn't created yet.
+		{path: path("pending"), wantKept: true},
+		{path: path("pending") + dump.ModulesExt, wantKept: true},
+		{path: path("pending") + dump.PendingExt, wantKept: true},
+		{path: filepath.Join(dumpDir, dump.OthersDir, "core.1"), wantKept: true},
+	}
+	for _, f := range files {
+		age := 2 * time.Hour
+		if f.young {
+			age = time.Minute
+		}
+		writeAged(t, f.path, age)
+	}
+
+	sw.sweep()
+
+	for _, f := range files {
+		if got := exists(f.path); got != f.wantKept {
+			t.Errorf("%s: kept = %v, want %v", strings.TrimPrefix(f.path, dumpDir), got, f.wantKept)
+		}
+	}
+	for _, name := range []string{"created", "allowed"} {
+		if cd, _ := client.GetCoredump("default", name); cd.Status.State == coredump.CoredumpStateDenied {
+			t.Errorf("%s is evicted without max cache size", name)
+		}
+	}
+}
+
+func TestSweepRemovesEmptyDirs(t *testing.T) {
+	client := newFakeCoredumpClient()
+	sw, cleanup := newTestSweeper(t, client, 0)
+	defer cleanup()
+	dumpDir := sw.saver.options.DumpDir
+	podDir := filepath.Join(dumpDir, "default", "web-uid")
+	writeAged(t, filepath.Join(podDir, "app", "orphan"), 2*time.Hour)
+
+	sw.sweep()
+
+	if exists(podDir) {
+		t.Errorf("directory of the pod %s is kept", podDir)
+	}
+	if !exists(dumpDir) {
+		t.Errorf("dump dir %s is removed", dumpDir)
+	}
+}
+
+func TestSweepEvictsOldest(t *testing.T) {
+	client := newFakeCoredumpClient(
+		newCoredump("oldest", coredump.CoredumpStateCreated),
+		newCoredump("older", coredump.CoredumpStateCreated),
+		newCoredump("old", coredump.CoredumpStateCreated),
+		newCoredump("allowed", coredump.CoredumpStateStateAllowed),
+	)
+	// 6 files of the same size are written, 2 of them must be evicted.
+	sw, cleanup := newTestSweeper(t, client, 0)
+	defer cleanup()
+	path := func(name string) string {
+		cd, _ := client.GetCoredump("default", name)
+		return dump.CachePath(sw.saver.options.DumpDir, cd)
+	}
+	// the allowed coredump is the oldest, but it's moved soon.
+	size := writeAged(t, path("allowed"), 5*time.Hour)
+	writeAged(t, path("oldest"), 4*time.Hour)
+	writeAged(t, path("oldest")+dump.ModulesExt, 4*time.Hour)
+	writeAged(t, path("older"), 3*time.Hour)
+	writeAged(t, path("old"), 2*time.Hour)
+	// a file just written, not evictable but counted in the host cache.
+	writeAged(t, path("old")+".young", time.Minute)
+	sw.maxCacheSize = 4 * size
+
+	sw.sweep()
+
+	for _, tc := range []struct {
+		name        string
+		wantEvicted bool
+	}{
+		{"oldest", true},
+		{"older", true},
+		{"old", false},
+		{"allowed", false},
+	} {
+		cd, _ := client.GetCoredump("default", tc.name)
+		evicted := cd.Status.State == coredump.CoredumpStateDenied
+		if evicted != tc.wantEvicted {
+			t.Errorf("%s: evicted = %v, want %v", tc.name, evicted, tc.wantEvicted)
+		}
+		if exists(path(tc.name)) == tc.wantEvicted {
+			t.Errorf("%s: file kept = %v, want %v", tc.name, tc.wantEvicted, !tc.wantEvicted)
+		}
+		if tc.wantEvicted && !strings.Contains(cd.Status.Message, "node1") {
+			t.Errorf("%s: message = %q, want the node of the host cache", tc.name, cd.Status.Message)
+		}
+	}
+	// sidecars aren't evicted, they follow their coredumps.
+	if !exists(path("oldest") + dump.ModulesExt) {
+		t.Error("sidecar of the evicted coredump is evicted")
+	}
+}
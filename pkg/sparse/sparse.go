@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparse
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"syscall"
+)
+
+// BlockSize is the size of blocks checked for zeros, it's the page size,
+// which is the unit of memory dumped in core files.
+const BlockSize = 4096
+
+var zeroBlock [BlockSize]byte
+
+// Writer writes a file sequentially, blocks of zeros aligned to BlockSize
+// are not written but seeked over, so that they become holes of the file.
+// A block split across writes, e.g. by a compressor, is buffered until it's
+// complete, so it's checked as a whole.
+type Writer struct {
+	f   *os.File
+	off int64
+	// block is the incomplete block at off, of buffered bytes.
+	block    [BlockSize]byte
+	buffered int
+}
+
+// NewWriter returns a writer which writes the file from its beginning.
+func NewWriter(f *os.File) *Writer {
+	return &Writer{f: f}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.buffered == 0 && len(p) >= BlockSize {
+			// a complete block, it's checked without buffering.
+			if err := w.writeBlock(p[:BlockSize]); err != nil {
+				return written, err
+			}
+			written += BlockSize
+			p = p[BlockSize:]
+			continue
+		}
+		n := copy(w.block[w.buffered:], p)
+		w.buffered += n
+		written += n
+		p = p[n:]
+		if w.buffered == BlockSize {
+			w.buffered = 0
+			if err := w.writeBlock(w.block[:]); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// writeBlock writes the block at off, unless it's all zeros.
+func (w *Writer) writeBlock(block []byte) error {
+	if !bytes.Equal(block, zeroBlock[:len(block)]) {
+		if _, err := w.f.WriteAt(block, w.off); err != nil {
+			return err
+		}
+	}
+	w.off += int64(len(block))
+	return nil
+}
+
+// Close writes the incomplete last block, and sets the size of the file, so
+// that trailing holes are kept. It doesn't close the file.
+func (w *Writer) Close() error {
+	if w.buffered > 0 {
+		buffered := w.buffered
+		w.buffered = 0
+		if err := w.writeBlock(w.block[:buffered]); err != nil {
+			return err
+		}
+	}
+	return w.f.Truncate(w.off)
+}
+
+// Copy copies src to dst from the beginning of dst, and keeps blocks of
+// zeros as holes.
+func Copy(dst *os.File, src io.Reader) (int64, error) {
+	w := NewWriter(dst)
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// AllocatedSize returns the disk space allocated for the file, which is
+// less than its apparent size if the file has holes.
+func AllocatedSize(fi os.FileInfo) int64 {
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+		// st_blocks is in units of 512 bytes.
+		return stat.Blocks * 512
+	}
+	return fi.Size()
+}
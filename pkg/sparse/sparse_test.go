@@ -0,0 +1,138 @@
+/*
+Copyright 2017 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sparse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// holeSize is the size of zero runs in the test files, large enough that
+// file systems allocate less for the hole.
+const holeSize = 256 * BlockSize
+
+func data(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i%251) + 1
+	}
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zeros := make([]byte, holeSize)
+	for _, tc := range []struct {
+		name    string
+		content []byte
+		// sparse is true if the file must allocate less than its size.
+		sparse bool
+	}{
+		{"empty", nil, false},
+		{"data only", data(3*BlockSize + 10), false},
+		{"hole in the middle", concat(data(BlockSize), zeros, data(BlockSize)), true},
+		{"unaligned hole", concat(data(100), zeros, data(100)), true},
+		{"ends in a hole", concat(data(BlockSize+1), zeros), true},
+		{"starts with a hole", concat(zeros, data(10)), true},
+		{"zeros only", zeros, true},
+		{"short zeros", make([]byte, 10), false},
+	} {
+		// writes of a compressor are not aligned to blocks.
+		for _, chunk := range []int{1, 100, BlockSize - 1, BlockSize, BlockSize + 1, 3 * BlockSize, 1 << 20} {
+			filename := filepath.Join(dir, "core")
+			f, err := os.Create(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := NewWriter(f)
+			for p := tc.content; len(p) > 0; {
+				n := chunk
+				if n > len(p) {
+					n = len(p)
+				}
+				if written, err := w.Write(p[:n]); err != nil || written != n {
+					t.Fatalf("%s: Write = %d, %v, want %d", tc.name, written, err, n)
+				}
+				p = p[n:]
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: Close: %v", tc.name, err)
+			}
+			fi, err := f.Stat()
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.content) {
+				t.Errorf("%s, writes of %d: content differs, got %d bytes, want %d", tc.name, chunk, len(got), len(tc.content))
+			}
+			if allocated := AllocatedSize(fi); tc.sparse && allocated >= fi.Size() {
+				t.Errorf("%s, writes of %d: allocated %d bytes for %d bytes, want a hole", tc.name, chunk, allocated, fi.Size())
+			}
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := concat(data(10), make([]byte, holeSize), data(BlockSize), make([]byte, holeSize))
+	f, err := os.Create(filepath.Join(dir, "core"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n, err := Copy(f, bytes.NewReader(content))
+	if err != nil || n != int64(len(content)) {
+		t.Fatalf("Copy = %d, %v, want %d", n, err, len(content))
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("content differs, got %d bytes, want %d", len(got), len(content))
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocated := AllocatedSize(fi); allocated >= fi.Size() {
+		t.Errorf("allocated %d bytes for %d bytes, want holes", allocated, fi.Size())
+	}
+}